@@ -2,18 +2,34 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/forkspacer/api-server/pkg/api"
+	"github.com/forkspacer/api-server/pkg/api/admin"
+	"github.com/forkspacer/api-server/pkg/api/middleware"
 	apiv1 "github.com/forkspacer/api-server/pkg/api/v1"
+	apiv2 "github.com/forkspacer/api-server/pkg/api/v2"
+	"github.com/forkspacer/api-server/pkg/audit"
 	"github.com/forkspacer/api-server/pkg/config"
+	"github.com/forkspacer/api-server/pkg/health"
+	"github.com/forkspacer/api-server/pkg/metrics"
 	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	"github.com/forkspacer/api-server/pkg/tracing"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/client-go/discovery"
+	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "path to the YAML config file")
+	flag.Parse()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go listenForTermination(func() { cancel() })
@@ -23,7 +39,7 @@ func main() {
 		panic(err)
 	}
 
-	apiConfig, errs := config.NewAPIConfig()
+	apiConfig, errs := config.NewAPIConfig(*configFile)
 	if errs != nil {
 		for _, err := range errs.Errors {
 			logger.Error("Config error", zap.Error(err))
@@ -31,36 +47,236 @@ func main() {
 		return
 	}
 
-	// Switch to production logger if not in development mode.
-	if !apiConfig.Dev {
-		logger, err = zap.NewProduction()
-		if err != nil {
-			panic(err)
+	// The level is atomic so that it follows config file reloads
+	logLevel := zap.NewAtomicLevelAt(parseLogLevel(apiConfig))
+	logger, err = newLogger(apiConfig.Dev, logLevel)
+	if err != nil {
+		panic(err)
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    apiConfig.TracingExporter,
+		File:        apiConfig.TracingFile,
+		SampleRatio: apiConfig.TracingSampleRatio,
+	})
+	if err != nil {
+		logger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+	defer func() {
+		// The run context is already cancelled at this point
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Error("Failed to flush traces", zap.Error(err))
 		}
+	}()
+
+	namespacePolicy := forkspacer.NamespacePolicy{
+		Default: apiConfig.DefaultNamespace,
+		Allowed: apiConfig.AllowedNamespaces,
+	}
+	if err := namespacePolicy.Check(namespacePolicy.Default); err != nil {
+		logger.Fatal("Default namespace is not in the allowed namespaces", zap.Error(err))
 	}
 
-	forkspacerWorkspaceService, err := forkspacer.NewForkspacerWorkspaceService()
+	kubeClient, readinessChecks, err := newBackend(apiConfig, namespacePolicy)
 	if err != nil {
-		logger.Fatal("Failed to create Forkspacer workspace service", zap.Error(err))
+		logger.Fatal("Failed to create Kubernetes client", zap.Error(err), zap.String("backend", apiConfig.Backend))
 	}
+	go func() {
+		if err := kubeClient.Start(ctx); err != nil {
+			logger.Error("Kubernetes client stopped", zap.Error(err))
+		}
+	}()
 
-	forkspacerModuleService, err := forkspacer.NewForkspacerModuleService()
+	forkspacerWorkspaceService := forkspacer.NewForkspacerWorkspaceService(kubeClient, namespacePolicy)
+	forkspacerModuleService := forkspacer.NewForkspacerModuleService(kubeClient, namespacePolicy)
+
+	resourceCollector := forkspacer.NewResourceCollector(kubeClient, namespacePolicy)
+	metrics.Registry.MustRegister(resourceCollector)
+
+	auditor, err := newAuditor(logger, apiConfig, kubeClient)
 	if err != nil {
-		logger.Fatal("Failed to create Forkspacer module service", zap.Error(err))
+		logger.Fatal("Failed to create auditor", zap.Error(err))
+	}
+	go auditor.Run()
+
+	rateLimiter := middleware.NewRateLimiter(rateLimits(apiConfig))
+	// One limiter for both versions, so that the cap applies to the whole server
+	inFlightLimiter := middleware.NewInFlightLimiter(apiConfig.MaxInflightRequests)
+	idempotency := middleware.NewIdempotency(apiConfig.IdempotencyKeyTTL)
+	corsMiddleware := middleware.NewCORS(apiConfig.CORSAllowedOrigins)
+
+	configStore := config.NewStore(apiConfig)
+	if *configFile != "" {
+		go configStore.Watch(ctx, logger, *configFile, func(reloaded *config.APIConfig) {
+			logLevel.SetLevel(parseLogLevel(reloaded))
+			rateLimiter.SetLimits(rateLimits(reloaded))
+			corsMiddleware.SetAllowedOrigins(reloaded.CORSAllowedOrigins)
+		})
 	}
 
-	logger.Info("Starting API server", zap.Uint16("port", apiConfig.APIPort))
+	runOptions := api.RunOptions{
+		Addresses:       apiConfig.BindAddresses,
+		Port:            apiConfig.APIPort,
+		UnixSocket:      apiConfig.UnixSocket,
+		ReadinessChecks: readinessChecks,
+		Timeouts: api.ServerTimeouts{
+			ReadHeader: apiConfig.ReadHeaderTimeout,
+			Read:       apiConfig.ReadTimeout,
+			Write:      apiConfig.WriteTimeout,
+			Idle:       apiConfig.IdleTimeout,
+		},
+		ShutdownGracePeriod: apiConfig.ShutdownGracePeriod,
+		AdminAddresses:      apiConfig.AdminBindAddresses,
+		AdminPort:           apiConfig.AdminPort,
+		AdminHandler:        admin.NewRouter(logger, logLevel, configStore, auditor),
+	}
+	if apiConfig.TLSCertFile != "" {
+		runOptions.TLS = &api.TLSOptions{
+			CertFile:          apiConfig.TLSCertFile,
+			KeyFile:           apiConfig.TLSKeyFile,
+			ClientCAFile:      apiConfig.TLSClientCAFile,
+			RequireClientCert: apiConfig.TLSRequireClientCert,
+			ReloadInterval:    apiConfig.TLSReloadInterval,
+		}
+	}
+
+	logger.Info("Starting API server",
+		zap.Strings("addresses", apiConfig.BindAddresses),
+		zap.Uint16("port", apiConfig.APIPort),
+		zap.String("unixSocket", apiConfig.UnixSocket),
+		zap.Strings("adminAddresses", apiConfig.AdminBindAddresses),
+		zap.Uint16("adminPort", apiConfig.AdminPort),
+		zap.String("backend", apiConfig.Backend),
+		zap.Bool("tls", runOptions.TLS != nil),
+		zap.Bool("clientCertAuth", apiConfig.TLSClientCAFile != ""),
+	)
 
 	if err := api.Run(ctx,
-		apiConfig.APIPort,
-		apiv1.NewRouter(logger, forkspacerWorkspaceService, forkspacerModuleService),
+		logger,
+		runOptions,
+		api.VersionRouter{
+			Version: apiv1.Version,
+			Handler: apiv1.NewRouter(
+				logger, configStore, corsMiddleware, auditor, rateLimiter, inFlightLimiter, idempotency,
+				forkspacerWorkspaceService, forkspacerModuleService,
+			),
+		},
+		api.VersionRouter{
+			Version: apiv2.Version,
+			Handler: apiv2.NewRouter(
+				logger, configStore, corsMiddleware, auditor, rateLimiter, inFlightLimiter, idempotency,
+				forkspacerWorkspaceService, forkspacerModuleService,
+			),
+		},
 	); err != nil {
 		logger.Error("API server failed to run", zap.Error(err), zap.Uint16("port", apiConfig.APIPort))
 	}
 
+	// Requests are over, so the entries they recorded are all queued
+	auditor.Close()
+
 	logger.Info("API server stopped", zap.Uint16("port", apiConfig.APIPort))
 }
 
+func newLogger(dev bool, level zap.AtomicLevel) (*zap.Logger, error) {
+	loggerConfig := zap.NewProductionConfig()
+	if dev {
+		loggerConfig = zap.NewDevelopmentConfig()
+	}
+	loggerConfig.Level = level
+
+	return loggerConfig.Build()
+}
+
+// parseLogLevel falls back to debug in development mode and info otherwise when no level is set.
+func parseLogLevel(apiConfig *config.APIConfig) zapcore.Level {
+	if apiConfig.LogLevel == "" {
+		if apiConfig.Dev {
+			return zapcore.DebugLevel
+		}
+		return zapcore.InfoLevel
+	}
+
+	// The level is already validated by the config
+	level, _ := zapcore.ParseLevel(apiConfig.LogLevel)
+	return level
+}
+
+func rateLimits(apiConfig *config.APIConfig) (read, write middleware.RateLimit) {
+	read = middleware.RateLimit{
+		RequestsPerSecond: apiConfig.RateLimitReadRPS,
+		Burst:             apiConfig.RateLimitReadBurst,
+	}
+	write = middleware.RateLimit{
+		RequestsPerSecond: apiConfig.RateLimitWriteRPS,
+		Burst:             apiConfig.RateLimitWriteBurst,
+	}
+	return read, write
+}
+
+// newBackend returns the client the services run against and the checks deciding readiness.
+func newBackend(
+	apiConfig *config.APIConfig,
+	namespacePolicy forkspacer.NamespacePolicy,
+) (*forkspacer.Client, []health.Check, error) {
+	if apiConfig.Backend == "memory" {
+		kubeClient, err := forkspacer.NewMemoryClient(forkspacer.MemoryOptions{
+			FixturesFile:       apiConfig.MemoryFixturesFile,
+			DefaultNamespace:   apiConfig.DefaultNamespace,
+			TransitionInterval: apiConfig.MemoryPhaseInterval,
+		})
+		return kubeClient, nil, err
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	}
+
+	kubeClient, err := forkspacer.NewClient(restConfig, forkspacer.ClientOptions{
+		QPS:        float32(apiConfig.KubernetesQPS),
+		Burst:      apiConfig.KubernetesBurst,
+		Namespaces: namespacePolicy.CacheNamespaces(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+
+	return kubeClient, []health.Check{
+		health.KubernetesAPICheck(discoveryClient),
+		health.ResourcesServedCheck(discoveryClient, batchv1.GroupVersion, "workspaces", "modules"),
+		health.CacheSyncedCheck("kubernetes-cache", kubeClient.WaitForCacheSync),
+	}, nil
+}
+
+func newAuditor(
+	logger *zap.Logger, apiConfig *config.APIConfig, kubeClient *forkspacer.Client,
+) (*audit.Auditor, error) {
+	sinks := make([]audit.Sink, 0, len(apiConfig.AuditSinks))
+
+	for _, sinkName := range apiConfig.AuditSinks {
+		switch sinkName {
+		case "file":
+			fileSink, err := audit.NewFileSink(apiConfig.AuditFile)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, fileSink)
+		case "zap":
+			sinks = append(sinks, audit.NewZapSink(logger))
+		case "events":
+			sinks = append(sinks, audit.NewEventSink(kubeClient))
+		}
+	}
+
+	return audit.NewAuditor(logger, apiConfig.AuditBufferSize, apiConfig.DefaultNamespace, sinks...), nil
+}
+
 func listenForTermination(do func()) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
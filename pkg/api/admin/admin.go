@@ -0,0 +1,143 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	apimiddleware "github.com/forkspacer/api-server/pkg/api/middleware"
+	"github.com/forkspacer/api-server/pkg/api/response"
+	"github.com/forkspacer/api-server/pkg/api/validation"
+	"github.com/forkspacer/api-server/pkg/audit"
+	"github.com/forkspacer/api-server/pkg/config"
+	"github.com/forkspacer/api-server/pkg/version"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewRouter serves the operational endpoints meant for the admin listener only:
+// profiling, build and runtime info, log level control, the effective config and the audit log.
+func NewRouter(
+	logger *zap.Logger, logLevel zap.AtomicLevel, configStore *config.Store, auditor *audit.Auditor,
+) http.Handler {
+	handler := &Handler{
+		logger: logger, logLevel: logLevel, configStore: configStore, auditor: auditor, startTime: time.Now(),
+	}
+
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+
+	router.Route("/debug/pprof", func(r chi.Router) {
+		r.HandleFunc("/", pprof.Index)
+		r.HandleFunc("/cmdline", pprof.Cmdline)
+		r.HandleFunc("/symbol", pprof.Symbol)
+		// Profiles and traces can run for as long as the client asks
+		r.With(apimiddleware.Streaming).HandleFunc("/profile", pprof.Profile)
+		r.With(apimiddleware.Streaming).HandleFunc("/trace", pprof.Trace)
+		r.With(apimiddleware.Streaming).HandleFunc("/{profile}", pprof.Index)
+	})
+
+	router.Route("/admin", func(r chi.Router) {
+		r.Get("/info", handler.InfoHandle)
+		r.Get("/loglevel", handler.GetLogLevelHandle)
+		r.Put("/loglevel", handler.SetLogLevelHandle)
+		r.Get("/config", handler.ConfigHandle)
+		r.Get("/audit", handler.AuditHandle)
+	})
+
+	return router
+}
+
+type Handler struct {
+	logger      *zap.Logger
+	logLevel    zap.AtomicLevel
+	configStore *config.Store
+	auditor     *audit.Auditor
+	startTime   time.Time
+}
+
+type RuntimeInfo struct {
+	StartTime     time.Time `json:"startTime"`
+	Uptime        string    `json:"uptime"`
+	Goroutines    int       `json:"goroutines"`
+	GOMAXPROCS    int       `json:"gomaxprocs"`
+	NumCPU        int       `json:"numCPU"`
+	HeapAlloc     uint64    `json:"heapAllocBytes"`
+	HeapInuse     uint64    `json:"heapInuseBytes"`
+	Sys           uint64    `json:"sysBytes"`
+	NumGC         uint32    `json:"numGC"`
+	LastGCPauseNs uint64    `json:"lastGCPauseNs"`
+}
+
+type InfoResponse struct {
+	Build   version.BuildInfo `json:"build"`
+	Runtime RuntimeInfo       `json:"runtime"`
+}
+
+func (h Handler) InfoHandle(w http.ResponseWriter, r *http.Request) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, InfoResponse{
+		Build: version.Info(),
+		Runtime: RuntimeInfo{
+			StartTime:     h.startTime,
+			Uptime:        time.Since(h.startTime).Round(time.Second).String(),
+			Goroutines:    runtime.NumGoroutine(),
+			GOMAXPROCS:    runtime.GOMAXPROCS(0),
+			NumCPU:        runtime.NumCPU(),
+			HeapAlloc:     memStats.HeapAlloc,
+			HeapInuse:     memStats.HeapInuse,
+			Sys:           memStats.Sys,
+			NumGC:         memStats.NumGC,
+			LastGCPauseNs: memStats.PauseNs[(memStats.NumGC+255)%256],
+		},
+	}))
+}
+
+type LogLevelRequest struct {
+	Level string `json:"level" validate:"required,oneof=debug info warn error"`
+}
+
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+func (h Handler) GetLogLevelHandle(w http.ResponseWriter, r *http.Request) {
+	response.JSONSuccess(w, 200,
+		response.NewJSONSuccess(response.SuccessCodes.Ok, LogLevelResponse{Level: h.logLevel.Level().String()}),
+	)
+}
+
+func (h Handler) SetLogLevelHandle(w http.ResponseWriter, r *http.Request) {
+	var requestData = &LogLevelRequest{}
+	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
+		return
+	}
+
+	// The level is already validated against the names zap understands
+	level, _ := zapcore.ParseLevel(requestData.Level)
+
+	previous := h.logLevel.Level()
+	h.logLevel.SetLevel(level)
+	h.logger.Info("log level changed", zap.Stringer("from", previous), zap.Stringer("to", level))
+
+	response.JSONSuccess(w, 200,
+		response.NewJSONSuccess(response.SuccessCodes.Ok, LogLevelResponse{Level: level.String()}),
+	)
+}
+
+// ConfigHandle returns the effective config, keyed by config file keys, with sensitive settings redacted.
+func (h Handler) ConfigHandle(w http.ResponseWriter, r *http.Request) {
+	effectiveConfig, err := h.configStore.Load().Redacted()
+	if err != nil {
+		h.logger.Error("failed to render effective config", zap.Error(err))
+		response.JSONInternal(w)
+		return
+	}
+
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, effectiveConfig))
+}
@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+	"github.com/forkspacer/api-server/pkg/api/validation"
+	"github.com/forkspacer/api-server/pkg/audit"
+	"github.com/forkspacer/api-server/pkg/utils"
+)
+
+type ListAuditEntriesRequestQuery struct {
+	User      *string `json:"user,omitempty" validate:"omitempty,min=1"`
+	Kind      *string `json:"kind,omitempty" validate:"omitempty,oneof=Workspace Module Secret"`
+	Name      *string `json:"name,omitempty" validate:"omitempty,dns1123subdomain"`
+	Namespace *string `json:"namespace,omitempty" validate:"omitempty,dns1123label"`
+	Limit     *int64  `json:"limit,omitempty" validate:"omitempty,gte=1,lte=1000"`
+}
+
+type ListAuditEntriesResponse struct {
+	Entries []audit.Entry `json:"entries"`
+}
+
+// AuditHandle lists the most recent audit entries, newest first. Entries span every namespace,
+// which is why they are only served on the admin listener.
+func (h Handler) AuditHandle(w http.ResponseWriter, r *http.Request) {
+	var requestData = &ListAuditEntriesRequestQuery{}
+
+	if r.URL.Query().Has("limit") {
+		qLimit, err := utils.ParseString[int64](r.URL.Query().Get("limit"))
+		if err != nil {
+			response.JSONBadRequest(w, err.Error())
+			return
+		}
+		requestData.Limit = &qLimit
+	}
+
+	for param, target := range map[string]**string{
+		"user":      &requestData.User,
+		"kind":      &requestData.Kind,
+		"name":      &requestData.Name,
+		"namespace": &requestData.Namespace,
+	} {
+		if r.URL.Query().Has(param) {
+			*target = utils.ToPtr(r.URL.Query().Get(param))
+		}
+	}
+
+	if err := validation.URLParamsValidate(r.Context(), w, requestData); err != nil {
+		return
+	}
+
+	if requestData.Limit == nil {
+		requestData.Limit = utils.ToPtr[int64](100)
+	}
+
+	filter := audit.Filter{Limit: int(*requestData.Limit)}
+	if requestData.User != nil {
+		filter.User = *requestData.User
+	}
+	if requestData.Kind != nil {
+		filter.Kind = *requestData.Kind
+	}
+	if requestData.Name != nil {
+		filter.Name = *requestData.Name
+	}
+	if requestData.Namespace != nil {
+		filter.Namespace = *requestData.Namespace
+	}
+
+	response.JSONSuccess(w, 200,
+		response.NewJSONSuccess(
+			response.SuccessCodes.Ok,
+			ListAuditEntriesResponse{Entries: h.auditor.Query(filter)},
+		),
+	)
+}
@@ -2,44 +2,257 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"io/fs"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/forkspacer/api-server/pkg/api/identity"
+	apimiddleware "github.com/forkspacer/api-server/pkg/api/middleware"
+	"github.com/forkspacer/api-server/pkg/health"
+	"github.com/forkspacer/api-server/pkg/metrics"
+	"github.com/forkspacer/api-server/pkg/tracing"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
 )
 
-func Run(ctx context.Context, port uint16, routers ...http.Handler) error {
+// healthCheckTimeout bounds each individual health check.
+const healthCheckTimeout = 5 * time.Second
+
+// Timeouts applied to every connection. A zero value disables the timeout.
+type ServerTimeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	// Write does not apply to routes using the Streaming middleware.
+	Write time.Duration
+	Idle  time.Duration
+}
+
+type RunOptions struct {
+	// Addresses lists the hosts or IPs the API and admin listeners bind to. All interfaces are used when empty.
+	Addresses []string
+	Port      uint16
+	// UnixSocket additionally serves the API over plain HTTP on a Unix domain socket at this path.
+	UnixSocket string
+	// TLS enables HTTPS serving on Port. Plain HTTP is served when nil.
+	TLS *TLSOptions
+	// ReadinessChecks must all pass for /readyz to report ready.
+	ReadinessChecks []health.Check
+
+	Timeouts ServerTimeouts
+	// ShutdownGracePeriod is how long in-flight requests may run after shutdown starts.
+	// Streaming requests are ended right away.
+	ShutdownGracePeriod time.Duration
+
+	// AdminHandler is served over plain HTTP on AdminPort of AdminAddresses, apart from the API.
+	// The admin listener is disabled when either AdminPort or AdminHandler is unset, and binds all
+	// interfaces when AdminAddresses is empty.
+	AdminAddresses []string
+	AdminPort      uint16
+	AdminHandler   http.Handler
+}
+
+// VersionRouter serves one version of the API under /api/<Version>.
+type VersionRouter struct {
+	Version string
+	Handler http.Handler
+}
+
+type listener struct {
+	server   *http.Server
+	listener net.Listener
+	tls      bool
+}
+
+func Run(ctx context.Context, logger *zap.Logger, options RunOptions, routers ...VersionRouter) error {
+	inFlight := newInFlightTracker()
+
 	baseRouter := chi.NewRouter()
 
+	baseRouter.Use(tracing.HTTPMiddleware)
+	baseRouter.Use(apimiddleware.RequestID(logger))
+	baseRouter.Use(apimiddleware.ContentNegotiation)
+	baseRouter.Use(apimiddleware.Locale)
+	baseRouter.Use(inFlight.Middleware)
+	baseRouter.Use(metrics.HTTPMiddleware)
 	baseRouter.Use(middleware.Recoverer)
+	baseRouter.Use(identity.ClientCertificateMiddleware)
+
+	// Liveness only proves the server is serving; dependencies are checked by readiness
+	baseRouter.Get("/healthz", health.Handler(healthCheckTimeout))
+	baseRouter.Get("/readyz", health.Handler(healthCheckTimeout, options.ReadinessChecks...))
+	baseRouter.Handle("/metrics", metrics.Handler())
+
 	for _, router := range routers {
-		baseRouter.Mount("/api", router)
+		baseRouter.Mount("/api/"+router.Version, router.Handler)
 	}
 
-	httpServer := &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: baseRouter,
+	var tlsConfig *tls.Config
+	if options.TLS != nil {
+		reloader, err := newTLSReloader(logger, *options.TLS)
+		if err != nil {
+			return fmt.Errorf("error while loading TLS certificates: %v", err)
+		}
+		tlsConfig = reloader.tlsConfig()
+
+		watchCtx, watchCancel := context.WithCancel(ctx)
+		defer watchCancel()
+		go reloader.watch(watchCtx)
 	}
 
-	listenerErrChan := make(chan error)
-	go func() {
-		listenerErrChan <- httpServer.ListenAndServe()
-	}()
+	// Closed when shutdown starts, so that streaming requests end instead of holding up draining
+	shuttingDown := make(chan struct{})
+
+	newServer := func(handler http.Handler) *http.Server {
+		return &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: options.Timeouts.ReadHeader,
+			ReadTimeout:       options.Timeouts.Read,
+			WriteTimeout:      options.Timeouts.Write,
+			IdleTimeout:       options.Timeouts.Idle,
+			BaseContext: func(net.Listener) context.Context {
+				return apimiddleware.WithShutdownSignal(context.Background(), shuttingDown)
+			},
+		}
+	}
+
+	hosts := options.Addresses
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+
+	var listeners []listener
+	closeListeners := func() {
+		for _, l := range listeners {
+			_ = l.listener.Close()
+		}
+	}
+
+	for _, host := range hosts {
+		apiListener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(int(options.Port))))
+		if err != nil {
+			closeListeners()
+			return fmt.Errorf("error while listening: %v", err)
+		}
+		server := newServer(baseRouter)
+		server.TLSConfig = tlsConfig
+		listeners = append(listeners, listener{server: server, listener: apiListener, tls: tlsConfig != nil})
+	}
 
+	if options.AdminPort != 0 && options.AdminHandler != nil {
+		adminHosts := options.AdminAddresses
+		if len(adminHosts) == 0 {
+			adminHosts = []string{""}
+		}
+
+		for _, host := range adminHosts {
+			adminListener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(int(options.AdminPort))))
+			if err != nil {
+				closeListeners()
+				return fmt.Errorf("error while listening for admin requests: %v", err)
+			}
+			listeners = append(listeners, listener{
+				server:   newServer(inFlight.Middleware(options.AdminHandler)),
+				listener: adminListener,
+			})
+		}
+	}
+
+	if options.UnixSocket != "" {
+		socketListener, err := listenUnix(options.UnixSocket)
+		if err != nil {
+			closeListeners()
+			return err
+		}
+		// Every caller on the socket has the same remote address, so callers are told apart by their process
+		socketServer := newServer(baseRouter)
+		socketServer.ConnContext = func(ctx context.Context, conn net.Conn) context.Context {
+			if peer := unixPeer(conn); peer != "" {
+				return apimiddleware.WithPeer(ctx, peer)
+			}
+			return ctx
+		}
+		listeners = append(listeners, listener{server: socketServer, listener: socketListener})
+	}
+
+	listenerErrChan := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func() {
+			if l.tls {
+				// Certificates are served from TLSConfig so no files are passed here
+				listenerErrChan <- l.server.ServeTLS(l.listener, "", "")
+				return
+			}
+			listenerErrChan <- l.server.Serve(l.listener)
+		}()
+	}
+
+	var serveErr error
 	select {
 	case err := <-listenerErrChan:
 		if err != nil && err != http.ErrServerClosed {
-			return fmt.Errorf("error while serving http: %v", err)
+			serveErr = fmt.Errorf("error while serving http: %v", err)
 		}
 	case <-ctx.Done():
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second*10)
-		defer shutdownCancel()
-		if err := httpServer.Shutdown(shutdownCtx); err != nil {
-			return fmt.Errorf("error while shutting down http server: %v", err)
+	}
+
+	logger.Info("draining in-flight requests",
+		zap.Int("requests", inFlight.count()),
+		zap.Duration("gracePeriod", options.ShutdownGracePeriod),
+	)
+	close(shuttingDown)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), options.ShutdownGracePeriod)
+	defer shutdownCancel()
+
+	var (
+		wg       sync.WaitGroup
+		timedOut atomic.Bool
+	)
+	for _, l := range listeners {
+		wg.Go(func() {
+			if err := l.server.Shutdown(shutdownCtx); err != nil {
+				timedOut.Store(true)
+			}
+		})
+	}
+	wg.Wait()
+
+	if timedOut.Load() {
+		inFlight.logRemaining(logger, "request cut off by shutdown")
+		for _, l := range listeners {
+			_ = l.server.Close()
 		}
 	}
 
-	return nil
+	return serveErr
+}
+
+// listenUnix listens on a Unix domain socket, replacing a socket left behind by a previous run.
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil && info.Mode().Type() == fs.ModeSocket {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %v", path, err)
+		}
+	}
+
+	socketListener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("error while listening on unix socket: %v", err)
+	}
+
+	// Allow local tooling running under the same group, but nobody else
+	if err := os.Chmod(path, 0o660); err != nil {
+		_ = socketListener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %v", err)
+	}
+
+	return socketListener, nil
 }
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	apimiddleware "github.com/forkspacer/api-server/pkg/api/middleware"
+	"go.uber.org/zap"
+)
+
+type inFlightRequest struct {
+	method    string
+	path      string
+	requestID string
+	start     time.Time
+}
+
+// inFlightTracker records the requests being served, so that those still running when the
+// shutdown grace period ends can be reported.
+type inFlightTracker struct {
+	mu       sync.Mutex
+	requests map[*http.Request]inFlightRequest
+}
+
+func newInFlightTracker() *inFlightTracker {
+	return &inFlightTracker{requests: map[*http.Request]inFlightRequest{}}
+}
+
+func (t *inFlightTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.mu.Lock()
+		t.requests[r] = inFlightRequest{
+			method:    r.Method,
+			path:      r.URL.Path,
+			requestID: apimiddleware.RequestIDFromContext(r.Context()),
+			start:     time.Now(),
+		}
+		t.mu.Unlock()
+
+		defer func() {
+			t.mu.Lock()
+			delete(t.requests, r)
+			t.mu.Unlock()
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (t *inFlightTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.requests)
+}
+
+// logRemaining logs every request that is still being served.
+func (t *inFlightTracker) logRemaining(logger *zap.Logger, message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, request := range t.requests {
+		logger.Warn(message,
+			zap.String("method", request.method),
+			zap.String("path", request.path),
+			zap.String("requestId", request.requestID),
+			zap.Duration("elapsed", time.Since(request.start)),
+		)
+	}
+}
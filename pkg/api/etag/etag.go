@@ -0,0 +1,70 @@
+package etag
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Entity tags are the quoted resourceVersion of the object. A resourceVersion changes
+// on every write, including status updates, so the tags are strong.
+
+var ErrInvalidIfMatch = errors.New("the If-Match header must be a single strong entity tag or *")
+
+// Set sets the ETag header to the resourceVersion of the object in the response.
+func Set(w http.ResponseWriter, resourceVersion string) {
+	if resourceVersion != "" {
+		w.Header().Set("ETag", `"`+resourceVersion+`"`)
+	}
+}
+
+// IfMatch returns the resourceVersion the If-Match header requires, or nil when any version matches.
+func IfMatch(r *http.Request) (*string, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return nil, nil
+	}
+
+	// Weak tags never match in If-Match
+	resourceVersion, ok := unquote(value)
+	if !ok {
+		return nil, ErrInvalidIfMatch
+	}
+
+	return &resourceVersion, nil
+}
+
+// NoneMatch reports whether the If-None-Match header lists the resourceVersion, i.e. whether
+// the client already holds the current object. Weak tags match as well.
+func NoneMatch(r *http.Request, resourceVersion string) bool {
+	value := r.Header.Get("If-None-Match")
+	if value == "" {
+		return false
+	}
+
+	for tag := range strings.SplitSeq(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if unquoted, ok := unquote(strings.TrimPrefix(tag, "W/")); ok && unquoted == resourceVersion {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NotModified answers a conditional GET whose If-None-Match matched.
+func NotModified(w http.ResponseWriter, resourceVersion string) {
+	Set(w, resourceVersion)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+func unquote(tag string) (string, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' || strings.Contains(tag[1:len(tag)-1], `"`) {
+		return "", false
+	}
+
+	return tag[1 : len(tag)-1], true
+}
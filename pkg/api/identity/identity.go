@@ -0,0 +1,82 @@
+package identity
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const Anonymous = "anonymous"
+
+// Identity describes the caller of a request.
+type Identity struct {
+	User   string   `json:"user"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+type contextKey struct{}
+
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext returns the identity stored in ctx, falling back to the anonymous identity.
+func FromContext(ctx context.Context) Identity {
+	if identity, ok := ctx.Value(contextKey{}).(Identity); ok {
+		return identity
+	}
+
+	return Identity{User: Anonymous}
+}
+
+// HeaderMiddleware takes the caller identity from headers set by an authenticating proxy.
+// Groups are read from a comma-separated header. Empty header names disable the lookup,
+// so the headers are only trusted when explicitly configured. An identity already
+// established from a verified client certificate is never overridden.
+func HeaderMiddleware(userHeader, groupsHeader string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, established := r.Context().Value(contextKey{}).(Identity); established || userHeader == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user := r.Header.Get(userHeader)
+			if user == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			identity := Identity{User: user}
+			if groupsHeader != "" {
+				for group := range strings.SplitSeq(r.Header.Get(groupsHeader), ",") {
+					if group = strings.TrimSpace(group); group != "" {
+						identity.Groups = append(identity.Groups, group)
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+// ClientCertificateMiddleware takes the caller identity from a verified TLS client certificate.
+// The subject common name becomes the user and the subject organizations become the groups.
+func ClientCertificateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		subject := r.TLS.VerifiedChains[0][0].Subject
+		if subject.CommonName == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity := Identity{User: subject.CommonName, Groups: subject.Organization}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
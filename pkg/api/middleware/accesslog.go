@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/api/identity"
+	"github.com/forkspacer/api-server/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AccessLog writes one log line per request once it completes. It must run after
+// the identity middlewares; the caller is added to the request-scoped logger as well.
+func AccessLog(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller := identity.FromContext(r.Context())
+			requestLogger := logging.FromContext(r.Context(), logger).With(zap.String("user", caller.User))
+			r = r.WithContext(logging.WithLogger(r.Context(), requestLogger))
+
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			route := r.URL.Path
+			if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil && routeCtx.RoutePattern() != "" {
+				route = routeCtx.RoutePattern()
+			}
+
+			level := zapcore.InfoLevel
+			if status >= 500 {
+				level = zapcore.ErrorLevel
+			}
+
+			requestLogger.Log(level, "request",
+				zap.String("method", r.Method),
+				zap.String("route", route),
+				zap.Int("status", status),
+				zap.Duration("latency", time.Since(start)),
+				zap.Int("bytes", ww.BytesWritten()),
+				zap.Strings("groups", caller.Groups),
+				zap.String("remoteAddr", r.RemoteAddr),
+			)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/go-chi/cors"
+)
+
+// CORS handles cross-origin requests with a list of allowed origins that can be replaced while serving.
+type CORS struct {
+	handler atomic.Pointer[cors.Cors]
+}
+
+func NewCORS(allowedOrigins []string) *CORS {
+	c := &CORS{}
+	c.SetAllowedOrigins(allowedOrigins)
+	return c
+}
+
+func (c *CORS) SetAllowedOrigins(allowedOrigins []string) {
+	c.handler.Store(cors.New(cors.Options{
+		AllowedOrigins: allowedOrigins,
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		// The defaults, plus the conditional request and idempotency headers
+		AllowedHeaders: []string{
+			"Origin", "Accept", "Content-Type", "X-Requested-With",
+			"If-Match", "If-None-Match", IdempotencyKeyHeader,
+		},
+		// Lets browser clients notice that v1 is deprecated, read the ETag of an object
+		// and tell replayed responses apart
+		ExposedHeaders: []string{"Deprecation", "Sunset", "Link", "ETag", IdempotentReplayedHeader},
+	}))
+}
+
+func (c *CORS) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.handler.Load().Handler(next).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Deprecation marks every response as deprecated since deprecatedAt (RFC 9745) and due to be
+// removed at sunset (RFC 8594). A non-empty successor is linked as the version replacing it.
+func Deprecation(deprecatedAt, sunset time.Time, successor string) func(http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Set("Sunset", sunsetDate)
+			if successor != "" {
+				w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed for a retried Idempotency-Key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// idempotencySweepInterval is how often expired responses are dropped.
+	idempotencySweepInterval = time.Minute
+)
+
+// replayedHeaders are the response headers stored along with a response. Others, such as
+// X-Request-ID, describe the request that is answered rather than the response.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+type idempotentResponse struct {
+	requestHash [sha256.Size]byte
+	// done is closed once the response below is recorded
+	done     chan struct{}
+	status   int
+	header   http.Header
+	body     []byte
+	storedAt time.Time
+}
+
+// Idempotency replays the response of a request retried with the same Idempotency-Key header.
+// Keys are scoped to the client, the same way as rate limits, and remembered by this server
+// only. Transient failures, such as 429 and 5xx responses, are not stored, so that a retry runs again.
+type Idempotency struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	responses map[string]*idempotentResponse
+	lastSweep time.Time
+}
+
+// NewIdempotency remembers responses for ttl. A zero ttl disables idempotency keys.
+func NewIdempotency(ttl time.Duration) *Idempotency {
+	return &Idempotency{
+		ttl:       ttl,
+		responses: map[string]*idempotentResponse{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (i *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || i.ttl <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			response.JSONBadRequest(w,
+				fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength),
+			)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				response.JSONBodyTooLarge(w, maxBytesErr.Limit)
+				return
+			}
+			response.JSONBadRequest(w, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		storeKey := clientKey(r) + "\x00" + key
+		requestHash := requestDigest(r, body)
+
+		stored, exists := i.reserve(storeKey, requestHash)
+		if exists {
+			replay(w, stored, requestHash)
+			return
+		}
+
+		recorder := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			// An error after the request timed out or was cancelled is not what the client got, if anything
+			transient := !completed || recorder.status == http.StatusTooManyRequests || recorder.status >= 500 ||
+				(recorder.status >= 400 && r.Context().Err() != nil)
+			i.record(storeKey, stored, recorder, transient)
+		}()
+
+		next.ServeHTTP(recorder, r)
+		completed = true
+	})
+}
+
+// requestDigest identifies a request by its method, URL, negotiated response media type and body,
+// so that a stored response is only replayed in the format it was asked for. Multipart bodies are
+// digested part by part, since clients pick a new boundary when they retry.
+func requestDigest(r *http.Request, body []byte) [sha256.Size]byte {
+	digest := sha256.New()
+	fmt.Fprintf(digest, "%s %s\n%s\n", r.Method, r.URL.RequestURI(), response.MediaType(r.Context()))
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && mediaType == "multipart/form-data" && params["boundary"] != "" {
+		if parts, err := digestParts(body, params["boundary"]); err == nil {
+			digest.Write(parts)
+			return [sha256.Size]byte(digest.Sum(nil))
+		}
+		// A malformed body is digested as is, the handler rejects it anyway
+	}
+
+	digest.Write(body)
+	return [sha256.Size]byte(digest.Sum(nil))
+}
+
+func digestParts(body []byte, boundary string) ([]byte, error) {
+	digest := sha256.New()
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return digest.Sum(nil), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(digest, "%q %q %d\n", part.FormName(), part.FileName(), len(content))
+		digest.Write(content)
+	}
+}
+
+// reserve returns the response stored for key, or reserves key for a new response.
+func (i *Idempotency) reserve(key string, requestHash [sha256.Size]byte) (*idempotentResponse, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(i.lastSweep) > idempotencySweepInterval {
+		for storeKey, stored := range i.responses {
+			if i.expired(stored, now) {
+				delete(i.responses, storeKey)
+			}
+		}
+		i.lastSweep = now
+	}
+
+	if stored, exists := i.responses[key]; exists && !i.expired(stored, now) {
+		return stored, true
+	}
+
+	stored := &idempotentResponse{requestHash: requestHash, done: make(chan struct{})}
+	i.responses[key] = stored
+	return stored, false
+}
+
+func (i *Idempotency) expired(stored *idempotentResponse, now time.Time) bool {
+	select {
+	case <-stored.done:
+		return now.Sub(stored.storedAt) > i.ttl
+	default:
+		// In progress
+		return false
+	}
+}
+
+// record stores the response of a reservation. A transient failure drops the reservation instead,
+// so that a retry runs the request again.
+func (i *Idempotency) record(key string, stored *idempotentResponse, recorder *recordingWriter, transient bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if transient {
+		delete(i.responses, key)
+		return
+	}
+
+	stored.status = recorder.status
+	stored.header = http.Header{}
+	for _, name := range replayedHeaders {
+		if value := recorder.Header().Get(name); value != "" {
+			stored.header.Set(name, value)
+		}
+	}
+	stored.body = recorder.body.Bytes()
+	stored.storedAt = time.Now()
+	close(stored.done)
+}
+
+func replay(w http.ResponseWriter, stored *idempotentResponse, requestHash [sha256.Size]byte) {
+	if stored.requestHash != requestHash {
+		response.JSONIdempotencyKeyReused(w)
+		return
+	}
+
+	select {
+	case <-stored.done:
+	default:
+		response.JSONConflict(w,
+			fmt.Sprintf("A request with this %s is still in progress", IdempotencyKeyHeader),
+		)
+		return
+	}
+
+	for name, values := range stored.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(stored.status)
+	_, _ = w.Write(stored.body)
+}
+
+// recordingWriter keeps a copy of the response it writes.
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.status = statusCode
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
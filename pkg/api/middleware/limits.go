@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+)
+
+// originalBodyContextKey holds the request body as received, before any BodyLimit wrapped it.
+type originalBodyContextKey struct{}
+
+// BodyLimit caps request bodies at limit bytes. Reading past the limit fails with *http.MaxBytesError.
+// A BodyLimit further down the chain replaces, rather than narrows, the one above it,
+// so routes can raise the limit as well as lower it. A zero limit disables the cap.
+func BodyLimit(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, ok := r.Context().Value(originalBodyContextKey{}).(io.ReadCloser)
+			if !ok {
+				body = r.Body
+				r = r.WithContext(context.WithValue(r.Context(), originalBodyContextKey{}, body))
+			}
+
+			if limit <= 0 {
+				r.Body = body
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Timeout sets a deadline on the request context, which bounds the Kubernetes calls made with it.
+// Error responses written after the deadline passed are replaced by a request_timeout error,
+// as are requests that end without a response. A zero timeout disables the deadline.
+func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{ResponseWriter: w, ctx: ctx}
+			next.ServeHTTP(tw, r.WithContext(ctx))
+
+			if !tw.wroteHeader && deadlineExceeded(ctx) {
+				response.JSONRequestTimeout(w)
+			}
+		})
+	}
+}
+
+func deadlineExceeded(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// timeoutWriter discards error responses written once the deadline passed, since they
+// describe the cancelled Kubernetes call rather than the request.
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	wroteHeader bool
+	discard     bool
+}
+
+func (w *timeoutWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if statusCode >= 400 && deadlineExceeded(w.ctx) {
+		w.discard = true
+		response.JSONRequestTimeout(w.ResponseWriter)
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discard {
+		return len(b), nil
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
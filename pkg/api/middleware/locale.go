@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/forkspacer/api-server/pkg/api/validation"
+)
+
+// Locale has the validation messages of each request translated to the language of its
+// Accept-Language header, English when none of its languages is supported.
+func Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+
+		ctx := validation.WithLanguage(r.Context(), validation.PreferredLanguage(r.Header.Get("Accept-Language")))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
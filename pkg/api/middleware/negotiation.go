@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+)
+
+// ContentNegotiation has the responses of the response package written as JSON or YAML, following the
+// Accept header of each request.
+func ContentNegotiation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(response.Negotiate(w, r))
+	})
+}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/api/identity"
+	"github.com/forkspacer/api-server/pkg/api/response"
+	"golang.org/x/time/rate"
+)
+
+// idleClientTTL is how long a client's buckets are kept after its last request.
+const idleClientTTL = 10 * time.Minute
+
+// RateLimit configures a token bucket. A zero RequestsPerSecond disables limiting.
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+type clientBuckets struct {
+	read     *rate.Limiter
+	write    *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter keeps separate read and write token buckets per client.
+// Clients are keyed by authenticated identity, then remote IP.
+type RateLimiter struct {
+	mu        sync.Mutex
+	read      RateLimit
+	write     RateLimit
+	clients   map[string]*clientBuckets
+	lastSweep time.Time
+}
+
+func NewRateLimiter(read, write RateLimit) *RateLimiter {
+	return &RateLimiter{
+		read:      read,
+		write:     write,
+		clients:   map[string]*clientBuckets{},
+		lastSweep: time.Now(),
+	}
+}
+
+// SetLimits replaces the read and write limits, including those of the buckets already handed out.
+func (l *RateLimiter) SetLimits(read, write RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.read = read
+	l.write = write
+
+	now := time.Now()
+	for _, buckets := range l.clients {
+		buckets.read.SetLimitAt(now, rate.Limit(read.RequestsPerSecond))
+		buckets.read.SetBurstAt(now, read.Burst)
+		buckets.write.SetLimitAt(now, rate.Limit(write.RequestsPerSecond))
+		buckets.write.SetBurstAt(now, write.Burst)
+	}
+}
+
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if retryAfter, allowed := l.allow(clientKey(r), isReadRequest(r)); !allowed {
+			response.JSONTooManyRequests(w, retryAfter)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *RateLimiter) allow(key string, read bool) (time.Duration, bool) {
+	now := time.Now()
+	limiter := l.limiterFor(key, read, now)
+	if limiter == nil {
+		return 0, true
+	}
+
+	reservation := limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return time.Second, false
+	}
+
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, false
+	}
+
+	return 0, true
+}
+
+// limiterFor returns the client's bucket, or nil when the limit is disabled.
+func (l *RateLimiter) limiterFor(key string, read bool, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if (read && l.read.RequestsPerSecond <= 0) || (!read && l.write.RequestsPerSecond <= 0) {
+		return nil
+	}
+
+	if now.Sub(l.lastSweep) > idleClientTTL {
+		for clientKey, buckets := range l.clients {
+			if now.Sub(buckets.lastSeen) > idleClientTTL {
+				delete(l.clients, clientKey)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	buckets, exists := l.clients[key]
+	if !exists {
+		buckets = &clientBuckets{
+			read:  rate.NewLimiter(rate.Limit(l.read.RequestsPerSecond), l.read.Burst),
+			write: rate.NewLimiter(rate.Limit(l.write.RequestsPerSecond), l.write.Burst),
+		}
+		l.clients[key] = buckets
+	}
+	buckets.lastSeen = now
+
+	if read {
+		return buckets.read
+	}
+	return buckets.write
+}
+
+func isReadRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+}
+
+type peerContextKey struct{}
+
+// WithPeer stores the local process a connection comes from, for connections whose remote address is the
+// same for every caller, such as those over a Unix socket.
+func WithPeer(ctx context.Context, peer string) context.Context {
+	return context.WithValue(ctx, peerContextKey{}, peer)
+}
+
+// clientKey identifies the caller of r by its identity, then the local process it runs as, then its IP.
+// Unverified headers are ignored, since a client could pick a new value for each request to get fresh buckets.
+func clientKey(r *http.Request) string {
+	if caller := identity.FromContext(r.Context()); caller.User != identity.Anonymous {
+		return "user:" + caller.User
+	}
+
+	if peer, ok := r.Context().Value(peerContextKey{}).(string); ok {
+		return "peer:" + peer
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// InFlightLimiter caps the number of requests being served concurrently across every router it is used in.
+// Requests over the cap are rejected instead of queued.
+type InFlightLimiter struct {
+	slots chan struct{}
+}
+
+// NewInFlightLimiter returns a limiter allowing limit concurrent requests. A zero limit disables the cap.
+func NewInFlightLimiter(limit int) *InFlightLimiter {
+	if limit <= 0 {
+		return &InFlightLimiter{}
+	}
+
+	return &InFlightLimiter{slots: make(chan struct{}, limit)}
+}
+
+func (l *InFlightLimiter) Middleware(next http.Handler) http.Handler {
+	if l.slots == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case l.slots <- struct{}{}:
+			defer func() { <-l.slots }()
+			next.ServeHTTP(w, r)
+		default:
+			response.JSONTooManyRequests(w, time.Second)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+	"github.com/forkspacer/api-server/pkg/logging"
+	"go.uber.org/zap"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+type requestIDContextKey struct{}
+
+// RequestIDFromContext returns the ID assigned to the request by RequestID, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// RequestID propagates the client's X-Request-ID or assigns a new one, echoes it in the
+// response and stores a logger tagged with it in the request context.
+func RequestID(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			fields := []zap.Field{zap.String("requestId", requestID)}
+			if traceID := w.Header().Get(response.TraceIDHeader); traceID != "" {
+				fields = append(fields, zap.String("traceId", traceID))
+			}
+
+			ctx := context.WithValue(r.Context(), requestIDContextKey{}, requestID)
+			ctx = logging.WithLogger(ctx, logger.With(fields...))
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// validRequestID only accepts printable ASCII so client-supplied IDs are safe to log and echo.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
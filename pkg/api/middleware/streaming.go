@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type shutdownContextKey struct{}
+
+// WithShutdownSignal stores a channel that is closed when the server starts shutting down.
+func WithShutdownSignal(ctx context.Context, shuttingDown <-chan struct{}) context.Context {
+	return context.WithValue(ctx, shutdownContextKey{}, shuttingDown)
+}
+
+// Streaming marks long-lived responses. They are exempt from the server's write timeout, and their
+// context is cancelled as soon as the server starts shutting down so they do not hold up draining.
+// Handlers may still set a deadline of their own, as pprof does for the duration of a profile.
+func Streaming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A zero deadline clears the write timeout for this response only
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if shuttingDown, ok := r.Context().Value(shutdownContextKey{}).(<-chan struct{}); ok {
+			go func() {
+				select {
+				case <-shuttingDown:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
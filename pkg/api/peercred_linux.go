@@ -0,0 +1,33 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// unixPeer identifies the process at the other end of a Unix socket connection by the user and process IDs
+// the kernel vouches for. It returns "" when they cannot be read.
+func unixPeer(conn net.Conn) string {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return ""
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return ""
+	}
+
+	var (
+		cred    *syscall.Ucred
+		credErr error
+	)
+	if err := rawConn.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil || credErr != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("uid=%d,pid=%d", cred.Uid, cred.Pid)
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixPeer(t *testing.T) {
+	socketListener, err := net.Listen("unix", filepath.Join(t.TempDir(), "api.sock"))
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer socketListener.Close()
+
+	clientConn, err := net.Dial("unix", socketListener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer clientConn.Close()
+
+	serverConn, err := socketListener.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept: %v", err)
+	}
+	defer serverConn.Close()
+
+	want := fmt.Sprintf("uid=%d,pid=%d", os.Getuid(), os.Getpid())
+	if got := unixPeer(serverConn); got != want {
+		t.Errorf("unixPeer() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package api
+
+import "net"
+
+// unixPeer returns "", since peer credentials are only read on Linux.
+// Unix socket callers then share their rate limits and idempotency keys.
+func unixPeer(net.Conn) string {
+	return ""
+}
@@ -12,18 +12,34 @@ var ErrCodes = struct {
 	BadRequest,
 	UnsupportedMediaType,
 	MalformedJSONBody,
+	MalformedYAMLBody,
 	BodyValidation,
 	QueryValidation,
-	FormDataTooLarge errCode
+	FormDataTooLarge,
+	TooManyRequests,
+	RequestTimeout,
+	Conflict,
+	Forbidden,
+	PreconditionFailed,
+	IdempotencyKeyReused,
+	Unavailable errCode
 }{
 	InternalServerError:  "internal_error",
 	NotFound:             "not_found",
 	BadRequest:           "bad_request",
 	UnsupportedMediaType: "unsupported_media_type",
 	MalformedJSONBody:    "malformed_json_body",
+	MalformedYAMLBody:    "malformed_yaml_body",
 	BodyValidation:       "body_validation",
 	QueryValidation:      "query_validation",
 	FormDataTooLarge:     "form_data_too_large",
+	TooManyRequests:      "too_many_requests",
+	RequestTimeout:       "request_timeout",
+	Conflict:             "conflict",
+	Forbidden:            "forbidden",
+	PreconditionFailed:   "precondition_failed",
+	IdempotencyKeyReused: "idempotency_key_reused",
+	Unavailable:          "unavailable",
 }
 
 var SuccessCodes = struct {
@@ -3,14 +3,21 @@ package response
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/forkspacer/api-server/pkg/utils"
 )
 
+// TraceIDHeader carries the trace ID of the request. Error responses repeat it in their body.
+const TraceIDHeader = "X-Trace-Id"
+
 type JSONErrorResponse struct {
-	Code errCode `json:"code"`
-	Data any     `json:"data"`
+	Code    errCode `json:"code"`
+	Data    any     `json:"data"`
+	TraceID string  `json:"traceId,omitempty"`
 }
 
 func NewJSONError(code errCode, data any) *JSONErrorResponse {
@@ -31,12 +38,19 @@ type Response struct {
 	Error   *JSONErrorResponse   `json:"error"`
 }
 
+// JSON writes data with its JSON encoding, or as YAML when Negotiate chose YAML for the response.
+// The Content-Type is set here, together with the body.
 func JSON(w http.ResponseWriter, statusCode int, data any) {
-	w.Header().Set("Content-Type", "application/json")
+	mediaType := negotiatedMediaType(w)
+	w.Header().Set("Content-Type", mediaType)
 	w.WriteHeader(statusCode)
 
 	if data != nil {
-		if err := json.NewEncoder(w).Encode(data); err != nil {
+		encode := json.NewEncoder(w).Encode
+		if mediaType == MediaTypeYAML {
+			encode = func(data any) error { return encodeYAML(w, data) }
+		}
+		if err := encode(data); err != nil {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 	}
@@ -55,6 +69,9 @@ func JSONDeleted(w http.ResponseWriter) {
 }
 
 func JSONError(w http.ResponseWriter, statusCode int, errorResponse *JSONErrorResponse) {
+	if traceID := w.Header().Get(TraceIDHeader); traceID != "" && errorResponse.TraceID == "" {
+		errorResponse.TraceID = traceID
+	}
 	JSON(w, statusCode, Response{Error: errorResponse})
 }
 
@@ -66,6 +83,11 @@ func JSONMalformedJSONBody(w http.ResponseWriter) {
 	JSONError(w, 400, NewJSONError(ErrCodes.MalformedJSONBody, nil))
 }
 
+// JSONMalformedYAMLBody rejects a YAML body that cannot be decoded, with the reason and its line when known.
+func JSONMalformedYAMLBody(w http.ResponseWriter, reason string) {
+	JSONError(w, 400, NewJSONError(ErrCodes.MalformedYAMLBody, reason))
+}
+
 func JSONBodyValidationError(w http.ResponseWriter, errs map[string]string) {
 	JSONError(w, 400, NewJSONError(ErrCodes.BodyValidation, errs))
 }
@@ -78,6 +100,29 @@ func JSONNotFound(w http.ResponseWriter) {
 	JSONError(w, 404, NewJSONError(ErrCodes.NotFound, "Not Found"))
 }
 
+// JSONUnprocessable rejects a well-formed body describing an object that the Kubernetes API refused.
+func JSONUnprocessable(w http.ResponseWriter, errs map[string]string) {
+	JSONError(w, 422, NewJSONError(ErrCodes.BodyValidation, errs))
+}
+
+func JSONForbidden(w http.ResponseWriter, data any) {
+	JSONError(w, 403, NewJSONError(ErrCodes.Forbidden, data))
+}
+
+func JSONConflict(w http.ResponseWriter, data any) {
+	JSONError(w, 409, NewJSONError(ErrCodes.Conflict, data))
+}
+
+func JSONPreconditionFailed(w http.ResponseWriter, data any) {
+	JSONError(w, 412, NewJSONError(ErrCodes.PreconditionFailed, data))
+}
+
+func JSONIdempotencyKeyReused(w http.ResponseWriter) {
+	JSONError(w, 422,
+		NewJSONError(ErrCodes.IdempotencyKeyReused, "Idempotency-Key was already used for a different request"),
+	)
+}
+
 func JSONFormDataTooLarge(w http.ResponseWriter, limit *int64) {
 	if limit == nil {
 		JSONError(w, 413,
@@ -94,6 +139,15 @@ func JSONFormDataTooLarge(w http.ResponseWriter, limit *int64) {
 	)
 }
 
+func JSONBodyTooLarge(w http.ResponseWriter, limit int64) {
+	JSONError(w, 413,
+		NewJSONError(
+			ErrCodes.FormDataTooLarge,
+			fmt.Sprintf("Request body too large (limit: %s)", utils.FormatBytes(limit)),
+		),
+	)
+}
+
 func JSONUnsopportedMediaType(w http.ResponseWriter, expectedMediaType string) {
 	JSONError(w, 415,
 		NewJSONError(
@@ -103,6 +157,29 @@ func JSONUnsopportedMediaType(w http.ResponseWriter, expectedMediaType string) {
 	)
 }
 
+func JSONTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	retryAfterSeconds := int(math.Ceil(retryAfter.Seconds()))
+	if retryAfterSeconds < 1 {
+		retryAfterSeconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+	JSONError(w, 429,
+		NewJSONError(
+			ErrCodes.TooManyRequests,
+			fmt.Sprintf("Too Many Requests (retry after: %ds)", retryAfterSeconds),
+		),
+	)
+}
+
 func JSONInternal(w http.ResponseWriter) {
 	JSONError(w, 500, NewJSONError(ErrCodes.InternalServerError, "Internal Server Error"))
 }
+
+func JSONRequestTimeout(w http.ResponseWriter) {
+	JSONError(w, 504, NewJSONError(ErrCodes.RequestTimeout, "Request timed out"))
+}
+
+func JSONServiceUnavailable(w http.ResponseWriter, data any) {
+	JSONError(w, 503, NewJSONError(ErrCodes.Unavailable, data))
+}
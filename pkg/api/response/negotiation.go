@@ -0,0 +1,149 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Media types responses are written in. JSON is used unless the request prefers YAML.
+const (
+	MediaTypeJSON = "application/json"
+	MediaTypeYAML = "application/yaml"
+)
+
+// IsYAMLMediaType reports whether mediaType, without parameters, is one of the media types YAML is sent as.
+func IsYAMLMediaType(mediaType string) bool {
+	switch mediaType {
+	case MediaTypeYAML, "application/x-yaml", "text/yaml":
+		return true
+	}
+	return false
+}
+
+// PreferredMediaType returns the media type of the responses to a request with the given Accept header.
+// YAML is only used when the header prefers it to JSON; unsupported media types fall back to JSON.
+func PreferredMediaType(accept string) string {
+	var jsonQuality, wildcardQuality, yamlQuality float64
+	jsonListed := false
+
+	for mediaRange := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		switch {
+		case mediaType == MediaTypeJSON:
+			jsonQuality, jsonListed = max(jsonQuality, quality), true
+		case mediaType == "*/*" || mediaType == "application/*":
+			wildcardQuality = max(wildcardQuality, quality)
+		case IsYAMLMediaType(mediaType):
+			yamlQuality = max(yamlQuality, quality)
+		}
+	}
+
+	// A listed media type takes precedence over the wildcards matching it
+	if !jsonListed {
+		jsonQuality = wildcardQuality
+	}
+	if yamlQuality > jsonQuality || (yamlQuality > 0 && yamlQuality == jsonQuality && !jsonListed) {
+		return MediaTypeYAML
+	}
+	return MediaTypeJSON
+}
+
+type mediaTypeContextKey struct{}
+
+// WithMediaType returns a context carrying the media type negotiated for the response to its request.
+func WithMediaType(ctx context.Context, mediaType string) context.Context {
+	return context.WithValue(ctx, mediaTypeContextKey{}, mediaType)
+}
+
+// MediaType returns the media type negotiated for the response to the request of ctx, JSON if none was.
+func MediaType(ctx context.Context) string {
+	if mediaType, ok := ctx.Value(mediaTypeContextKey{}).(string); ok {
+		return mediaType
+	}
+	return MediaTypeJSON
+}
+
+// negotiatedWriter carries the media type chosen by Negotiate to the responses written through this package,
+// which only receive the writer.
+type negotiatedWriter struct {
+	http.ResponseWriter
+	mediaType string
+}
+
+func (w *negotiatedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Negotiate chooses the media type r prefers for the responses written through this package.
+// It returns the writer and request carrying the choice, which r is to be served with.
+// Content-Type is only set once a response is written, so it cannot get out of step with the body.
+func Negotiate(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
+	w.Header().Add("Vary", "Accept")
+
+	mediaType := PreferredMediaType(r.Header.Get("Accept"))
+	return &negotiatedWriter{ResponseWriter: w, mediaType: mediaType}, r.WithContext(WithMediaType(r.Context(), mediaType))
+}
+
+// negotiatedMediaType returns the media type Negotiate chose for the response written to w,
+// looking through the writers wrapping the one it returned.
+func negotiatedMediaType(w http.ResponseWriter) string {
+	for {
+		switch writer := w.(type) {
+		case *negotiatedWriter:
+			return writer.mediaType
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return MediaTypeJSON
+		}
+	}
+}
+
+// encodeYAML writes data as YAML. Data goes through its JSON encoding first, so that the YAML document has
+// the field names and omissions of the JSON one, in the same order.
+func encodeYAML(w io.Writer, data any) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(jsonData, &document); err != nil {
+		return err
+	}
+	blockStyle(&document)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// blockStyle drops the flow style and quotes a node decoded from JSON has. Strings that would read as
+// another type are still quoted when encoded.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,45 @@
+package response
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestNegotiatedResponse(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+	}{
+		{name: "no preference", accept: "", wantContentType: MediaTypeJSON},
+		{name: "YAML", accept: "application/yaml", wantContentType: MediaTypeYAML},
+		{name: "JSON over YAML", accept: "application/yaml;q=0.5, application/json", wantContentType: MediaTypeJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Accept", tt.accept)
+			recorder := httptest.NewRecorder()
+
+			w, r := Negotiate(recorder, r)
+			if got := MediaType(r.Context()); got != tt.wantContentType {
+				t.Errorf("MediaType() = %q, want %q", got, tt.wantContentType)
+			}
+			if got := recorder.Header().Get("Content-Type"); got != "" {
+				t.Errorf("Content-Type = %q before the response is written", got)
+			}
+
+			// Middlewares wrapping the writer, or clearing the header, do not change the format
+			w = middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			w.Header().Del("Content-Type")
+			JSONNotFound(w)
+
+			if got := recorder.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+		})
+	}
+}
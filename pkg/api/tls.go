@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type TLSOptions struct {
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile enables client certificate verification against the given CA bundle.
+	ClientCAFile string
+	// RequireClientCert rejects connections without a verified client certificate.
+	// Otherwise client certificates are verified only when presented.
+	RequireClientCert bool
+
+	// ReloadInterval is how often the files are checked for changes.
+	ReloadInterval time.Duration
+}
+
+// tlsReloader serves the certificate and client CA bundle most recently read from disk.
+// Files are polled rather than watched so that atomic symlink swaps of mounted secrets are picked up.
+type tlsReloader struct {
+	logger  *zap.Logger
+	options TLSOptions
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+	fileStamps  []string
+}
+
+func newTLSReloader(logger *zap.Logger, options TLSOptions) (*tlsReloader, error) {
+	reloader := &tlsReloader{logger: logger, options: options}
+	if _, err := reloader.reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+func (r *tlsReloader) files() []string {
+	files := []string{r.options.CertFile, r.options.KeyFile}
+	if r.options.ClientCAFile != "" {
+		files = append(files, r.options.ClientCAFile)
+	}
+
+	return files
+}
+
+// reload reads the files again if any of them changed since the last successful load.
+func (r *tlsReloader) reload() (bool, error) {
+	stamps := make([]string, 0, 3)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		stamps = append(stamps, fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size()))
+	}
+
+	r.mu.RLock()
+	unchanged := slices.Equal(r.fileStamps, stamps)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.options.CertFile, r.options.KeyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.options.ClientCAFile != "" {
+		caBundle, err := os.ReadFile(r.options.ClientCAFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caBundle) {
+			return false, fmt.Errorf("client CA bundle %s contains no certificates", r.options.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.certificate = &certificate
+	r.clientCAs = clientCAs
+	r.fileStamps = stamps
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+func (r *tlsReloader) watch(ctx context.Context) {
+	interval := r.options.ReloadInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.reload()
+			if err != nil {
+				// Keep serving the previous certificate until the files are valid again
+				r.logger.Error("failed to reload TLS certificates", zap.Error(err))
+				continue
+			}
+			if reloaded {
+				r.logger.Info("reloaded TLS certificates", zap.String("certFile", r.options.CertFile))
+			}
+		}
+	}
+}
+
+func (r *tlsReloader) tlsConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.certificate, nil
+		},
+	}
+
+	if r.options.ClientCAFile == "" {
+		return config
+	}
+
+	clientAuth := tls.VerifyClientCertIfGiven
+	if r.options.RequireClientCert {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	// The client CA pool is resolved per handshake so that a reloaded bundle applies to new connections
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		clientConfig := config.Clone()
+		clientConfig.GetConfigForClient = nil
+		clientConfig.ClientAuth = clientAuth
+		clientConfig.ClientCAs = r.clientCAs
+		return clientConfig, nil
+	}
+
+	return config
+}
@@ -3,14 +3,26 @@ package v1
 import (
 	_ "embed"
 	"net/http"
+	"time"
 
+	"github.com/forkspacer/api-server/pkg/api/identity"
+	"github.com/forkspacer/api-server/pkg/api/middleware"
 	"github.com/forkspacer/api-server/pkg/api/v1/handlers"
+	"github.com/forkspacer/api-server/pkg/audit"
+	"github.com/forkspacer/api-server/pkg/config"
 	"github.com/forkspacer/api-server/pkg/services/forkspacer"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/cors"
 	"go.uber.org/zap"
 )
 
+const Version = "v1"
+
+// v1 is superseded by v2, which addresses every resource by its path.
+var (
+	deprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	sunsetAt     = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
 //go:embed docs.html
 var docsHTML []byte
 
@@ -19,13 +31,28 @@ var openAPISpec []byte
 
 func NewRouter(
 	logger *zap.Logger,
+	configStore *config.Store,
+	corsMiddleware *middleware.CORS,
+	auditor *audit.Auditor,
+	rateLimiter *middleware.RateLimiter,
+	inFlightLimiter *middleware.InFlightLimiter,
+	idempotency *middleware.Idempotency,
 	forkspacerWorkspaceService *forkspacer.ForkspacerWorkspaceService,
 	forkspacerModuleService *forkspacer.ForkspacerModuleService,
 ) http.Handler {
-	workspaceHandler := handlers.NewWorkspaceHandler(logger, forkspacerWorkspaceService)
+	// Settings read here only change on restart; reloadable ones are applied by their middlewares
+	apiConfig := configStore.Load()
+
+	workspaceHandler := handlers.NewWorkspaceHandler(
+		logger, forkspacerWorkspaceService, forkspacerModuleService, apiConfig.StrictKubeconfig,
+	)
 	moduleHandler := handlers.NewModuleHandler(logger, forkspacerModuleService)
 
 	apiRouter := chi.NewRouter()
+	apiRouter.Use(corsMiddleware.Middleware)
+	apiRouter.Use(identity.HeaderMiddleware(apiConfig.IdentityUserHeader, apiConfig.IdentityGroupsHeader))
+	apiRouter.Use(middleware.AccessLog(logger))
+	apiRouter.Use(middleware.Deprecation(deprecatedAt, sunsetAt, "/api/v2/docs"))
 
 	apiRouter.Get("/docs", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -44,35 +71,44 @@ func NewRouter(
 		}
 	})
 
-	apiRouter.Route("/workspace", func(r chi.Router) {
-		r.Post("/", workspaceHandler.CreateHandle)
-		r.Patch("/", workspaceHandler.UpdateHandle)
-		r.Delete("/", workspaceHandler.DeleteHandle)
-		r.Get("/list", workspaceHandler.ListHandle)
-
-		r.Route("/connection", func(r chi.Router) {
-			r.Route("/kubeconfig", func(r chi.Router) {
-				r.Post("/", workspaceHandler.CreateKubeconfigSecretHandle)
-				r.Delete("/", workspaceHandler.DeleteKubeconfigSecretHandle)
-				r.Get("/list", workspaceHandler.ListKubeconfigSecretsHandle)
-			})
-		})
-	})
+	apiRouter.Group(func(r chi.Router) {
+		r.Use(rateLimiter.Middleware)
+		r.Use(middleware.BodyLimit(apiConfig.MaxBodyBytes))
 
-	apiRouter.Route("/module", func(r chi.Router) {
-		r.Post("/", moduleHandler.CreateHandle)
-		r.Patch("/", moduleHandler.UpdateHandle)
-		r.Delete("/", moduleHandler.DeleteHandle)
-		r.Get("/list", moduleHandler.ListHandle)
-	})
+		// Routes hitting the Kubernetes API
+		r.Group(func(r chi.Router) {
+			r.Use(inFlightLimiter.Middleware)
+			r.Use(middleware.Timeout(apiConfig.RequestTimeout))
+
+			r.Route("/workspace", func(r chi.Router) {
+				r.Group(func(r chi.Router) {
+					r.Use(auditor.Middleware(audit.WorkspaceObject))
+					r.With(idempotency.Middleware).Post("/", workspaceHandler.CreateHandle)
+					r.Patch("/", workspaceHandler.UpdateHandle)
+					r.Delete("/", workspaceHandler.DeleteHandle)
+				})
+				r.Get("/list", workspaceHandler.ListHandle)
 
-	baseRouter := chi.NewRouter()
-	baseRouter.Use(cors.Handler(cors.Options{
-		AllowedOrigins: []string{"https://*", "http://*"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-	}))
+				r.Route("/connection", func(r chi.Router) {
+					r.Route("/kubeconfig", func(r chi.Router) {
+						r.Use(auditor.Middleware(audit.SecretObject))
+						r.With(middleware.BodyLimit(handlers.KubeconfigUploadMaxBytes), idempotency.Middleware).
+							Post("/", workspaceHandler.CreateKubeconfigSecretHandle)
+						r.Delete("/", workspaceHandler.DeleteKubeconfigSecretHandle)
+						r.Get("/list", workspaceHandler.ListKubeconfigSecretsHandle)
+					})
+				})
+			})
 
-	baseRouter.Mount("/v1", apiRouter)
+			r.Route("/module", func(r chi.Router) {
+				r.Use(auditor.Middleware(audit.ModuleObject))
+				r.With(idempotency.Middleware).Post("/", moduleHandler.CreateHandle)
+				r.Patch("/", moduleHandler.UpdateHandle)
+				r.Delete("/", moduleHandler.DeleteHandle)
+				r.Get("/list", moduleHandler.ListHandle)
+			})
+		})
+	})
 
-	return baseRouter
+	return apiRouter
 }
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+	"github.com/forkspacer/api-server/pkg/utils"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReadDryRun reads the dryRun query parameter of a mutating request. With dryRun=true the
+// Kubernetes API validates, defaults and admits the change without persisting it.
+func ReadDryRun(w http.ResponseWriter, r *http.Request) (bool, error) {
+	if !r.URL.Query().Has("dryRun") {
+		return false, nil
+	}
+
+	dryRun, err := utils.ParseString[bool](r.URL.Query().Get("dryRun"))
+	if err != nil {
+		response.JSONQueryValidationError(w, map[string]string{"dryRun": "dryRun must be true or false"})
+		return false, fmt.Errorf("invalid dryRun query parameter: %w", err)
+	}
+
+	return dryRun, nil
+}
+
+// DryRunOptions returns the client options of type O making a request a dry run, if dryRun is set.
+// O is one of client.CreateOption, client.UpdateOption and client.DeleteOption.
+func DryRunOptions[O any](dryRun bool) []O {
+	if !dryRun {
+		return nil
+	}
+
+	return []O{any(client.DryRunAll).(O)}
+}
+
+// DryRunObject returns obj, as the Kubernetes API would have stored it in a dry run, for the response
+// to the request. Responses to other creates and updates only name their object, but that would not
+// tell a dry run from a real change, or show the defaults the object would get.
+func DryRunObject(obj client.Object, kind string) client.Object {
+	obj.GetObjectKind().SetGroupVersionKind(batchv1.GroupVersion.WithKind(kind))
+	obj.SetManagedFields(nil)
+	return obj
+}
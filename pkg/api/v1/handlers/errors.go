@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceError logs a failed service call and responds with the status matching the error.
+func ServiceError(w http.ResponseWriter, r *http.Request, logger *zap.Logger, message string, err error) {
+	LogServiceError(r, logger, message, err)
+	JSONServiceError(w, err)
+}
+
+// JSONServiceError translates an error returned by a service, usually from the Kubernetes API,
+// into a response. Errors the API server did not return, other than connection failures,
+// come from checks on the request and are bad requests.
+func JSONServiceError(w http.ResponseWriter, err error) {
+	statusCode, errorResponse := ServiceErrorResponse(err)
+	response.JSONError(w, statusCode, errorResponse)
+}
+
+// ServiceErrorResponse returns the status code and error JSONServiceError responds with.
+func ServiceErrorResponse(err error) (int, *response.JSONErrorResponse) {
+	if errors.Is(err, forkspacer.ErrNamespaceNotAllowed) {
+		return 403, response.NewJSONError(response.ErrCodes.Forbidden, err.Error())
+	}
+	if errors.Is(err, forkspacer.ErrPreconditionFailed) {
+		return 412, response.NewJSONError(response.ErrCodes.PreconditionFailed, err.Error())
+	}
+
+	var apiStatus apierrors.APIStatus
+	if !errors.As(err, &apiStatus) {
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			return 503, response.NewJSONError(response.ErrCodes.Unavailable, "Kubernetes API is unreachable")
+		}
+
+		return 400, response.NewJSONError(response.ErrCodes.BadRequest, err.Error())
+	}
+
+	status := apiStatus.Status()
+	switch {
+	case apierrors.IsNotFound(err):
+		return 404, response.NewJSONError(response.ErrCodes.NotFound, status.Message)
+	case apierrors.IsAlreadyExists(err), apierrors.IsConflict(err):
+		return 409, response.NewJSONError(response.ErrCodes.Conflict, status.Message)
+	case apierrors.IsInvalid(err), isAdmissionDenial(status):
+		return 422, response.NewJSONError(response.ErrCodes.BodyValidation, statusCauses(status))
+	case apierrors.IsForbidden(err):
+		return 403, response.NewJSONError(response.ErrCodes.Forbidden, status.Message)
+	case apierrors.IsUnauthorized(err), apierrors.IsTooManyRequests(err), apierrors.IsServerTimeout(err),
+		apierrors.IsTimeout(err), status.Code >= http.StatusInternalServerError:
+		return 503, response.NewJSONError(response.ErrCodes.Unavailable, status.Message)
+	default:
+		return 400, response.NewJSONError(response.ErrCodes.BadRequest, status.Message)
+	}
+}
+
+// isAdmissionDenial reports whether an admission webhook, such as the operator's, rejected the object.
+// Webhooks answer with the status code of their choice, 403 unless they set one.
+func isAdmissionDenial(status metav1.Status) bool {
+	return strings.Contains(status.Message, "admission webhook") && strings.Contains(status.Message, "denied the request")
+}
+
+// statusCauses keys the causes of a rejected object by their field path, like body validation errors.
+// Causes without a field, and statuses without causes, are keyed as "object".
+func statusCauses(status metav1.Status) map[string]string {
+	errs := map[string]string{}
+
+	var causes []metav1.StatusCause
+	if status.Details != nil {
+		causes = status.Details.Causes
+	}
+	if len(causes) == 0 {
+		errs["object"] = status.Message
+		return errs
+	}
+
+	for _, cause := range causes {
+		field := cause.Field
+		if field == "" {
+			field = "object"
+		}
+		if existing, ok := errs[field]; ok {
+			errs[field] = existing + "; " + cause.Message
+			continue
+		}
+		errs[field] = cause.Message
+	}
+
+	return errs
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer/forkspacertest"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// handlerTest is a request to the v1 routes and the response expected from it.
+type handlerTest struct {
+	name   string
+	method string
+	target string
+	body   string
+	// funcs intercept the calls the services make to the backend, e.g. to fail them
+	funcs      interceptor.Funcs
+	wantStatus int
+	// wantCode is the code of the success or error in the response, empty when there is no body
+	wantCode string
+	// check inspects the response data and the backend after the request
+	check func(t *testing.T, kubeClient client.Client, data any)
+}
+
+func (tt handlerTest) run(t *testing.T, objects ...client.Object) {
+	t.Helper()
+
+	kubeClient := forkspacertest.NewClient(t, tt.funcs, objects...)
+	workspaceService := forkspacer.NewForkspacerWorkspaceService(kubeClient, forkspacertest.Namespaces)
+	moduleService := forkspacer.NewForkspacerModuleService(kubeClient, forkspacertest.Namespaces)
+
+	router := chi.NewRouter()
+	workspaceHandler := NewWorkspaceHandler(zap.NewNop(), workspaceService, moduleService, true)
+	router.Post("/workspace", workspaceHandler.CreateHandle)
+	router.Patch("/workspace", workspaceHandler.UpdateHandle)
+	router.Delete("/workspace", workspaceHandler.DeleteHandle)
+	router.Get("/workspace/list", workspaceHandler.ListHandle)
+	router.Delete("/workspace/connection/kubeconfig", workspaceHandler.DeleteKubeconfigSecretHandle)
+
+	moduleHandler := NewModuleHandler(zap.NewNop(), moduleService)
+	router.Post("/module", moduleHandler.CreateHandle)
+	router.Patch("/module", moduleHandler.UpdateHandle)
+	router.Delete("/module", moduleHandler.DeleteHandle)
+	router.Get("/module/list", moduleHandler.ListHandle)
+
+	r := forkspacertest.NewRequest(tt.method, tt.target, tt.body)
+	_, data := forkspacertest.Serve(t, router, r, tt.wantStatus, tt.wantCode)
+
+	if tt.check != nil {
+		tt.check(t, kubeClient, data)
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// ReadListSort reads the sort query parameter of a list request: a field, prefixed with "-" for descending order.
+func ReadListSort(w http.ResponseWriter, r *http.Request) (forkspacer.ListSort, error) {
+	if !r.URL.Query().Has("sort") {
+		return forkspacer.ListSort{}, nil
+	}
+
+	sort, ok := forkspacer.ParseListSort(r.URL.Query().Get("sort"))
+	if !ok {
+		fields := make([]string, len(forkspacer.SortFields))
+		for i, field := range forkspacer.SortFields {
+			fields[i] = string(field)
+		}
+		response.JSONQueryValidationError(w, map[string]string{
+			"sort": "sort must be one of " + strings.Join(fields, ", ") + ", optionally prefixed with -",
+		})
+		return forkspacer.ListSort{}, fmt.Errorf("invalid sort query parameter %q", r.URL.Query().Get("sort"))
+	}
+
+	return sort, nil
+}
+
+// ReadListFields reads the fields query parameter of a list request, a comma-separated list of
+// the JSON fields of Item to return. It returns nil when every field is to be returned.
+func ReadListFields[Item any](w http.ResponseWriter, r *http.Request) ([]string, error) {
+	if !r.URL.Query().Has("fields") {
+		return nil, nil
+	}
+
+	known := jsonFields(reflect.TypeFor[Item]())
+	var fields []string
+	for field := range strings.SplitSeq(r.URL.Query().Get("fields"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(known, field) {
+			response.JSONQueryValidationError(w, map[string]string{
+				"fields": fmt.Sprintf("unknown field %q, fields must be among %s", field, strings.Join(known, ", ")),
+			})
+			return nil, fmt.Errorf("unknown field %q in fields query parameter", field)
+		}
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// IncludesField reports whether a list response includes field, for fields that are costly to fill in.
+func IncludesField(fields []string, field string) bool {
+	return fields == nil || slices.Contains(fields, field)
+}
+
+// ProjectFields keeps only the given fields of each item. Name and namespace are always kept,
+// so that items can be told apart. Items are returned as they are when fields is nil.
+func ProjectFields[Item any](items []Item, fields []string) (any, error) {
+	if fields == nil {
+		return items, nil
+	}
+
+	projected := make([]map[string]json.RawMessage, len(items))
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+
+		all := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+
+		projected[i] = map[string]json.RawMessage{}
+		for field, value := range all {
+			if field == "name" || field == "namespace" || slices.Contains(fields, field) {
+				projected[i][field] = value
+			}
+		}
+	}
+
+	return projected, nil
+}
+
+func jsonFields(itemType reflect.Type) []string {
+	var fields []string
+	for i := range itemType.NumField() {
+		name, _, _ := strings.Cut(itemType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
+
+// ObjectAge returns the age of an object the way kubectl prints it, e.g. "3d4h".
+func ObjectAge(object metav1.Object) string {
+	createdAt := object.GetCreationTimestamp()
+	if createdAt.IsZero() {
+		return ""
+	}
+
+	return duration.HumanDuration(time.Since(createdAt.Time))
+}
+
+// StatusUpdatedAt returns when the operator last wrote the status of an object, from the managed
+// fields of the object. Not every write changes the phase, so this is not when the phase changed.
+// It is zero when unknown.
+func StatusUpdatedAt(object metav1.Object) time.Time {
+	var last time.Time
+	for _, entry := range object.GetManagedFields() {
+		if entry.Subresource == "status" && entry.Time != nil && entry.Time.After(last) {
+			last = entry.Time.Time
+		}
+	}
+
+	return last
+}
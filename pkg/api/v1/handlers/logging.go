@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/forkspacer/api-server/pkg/logging"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// LogServiceError logs a failed service call with the request-scoped logger.
+// Failures caused by the request itself, such as a missing object, are logged as warnings.
+func LogServiceError(r *http.Request, logger *zap.Logger, message string, err error) {
+	requestLogger := logging.FromContext(r.Context(), logger)
+
+	var apiStatus apierrors.APIStatus
+	if errors.Is(err, forkspacer.ErrNamespaceNotAllowed) || errors.Is(err, forkspacer.ErrPreconditionFailed) ||
+		(errors.As(err, &apiStatus) && apiStatus.Status().Code < http.StatusInternalServerError) {
+		requestLogger.Warn(message, zap.Error(err))
+		return
+	}
+
+	requestLogger.Error(message, zap.Error(err))
+}
@@ -3,15 +3,18 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/forkspacer/api-server/pkg/api/response"
 	"github.com/forkspacer/api-server/pkg/api/validation"
+	"github.com/forkspacer/api-server/pkg/logging"
 	"github.com/forkspacer/api-server/pkg/services/forkspacer"
 	"github.com/forkspacer/api-server/pkg/utils"
 	batchv1 "github.com/forkspacer/forkspacer/api/v1"
 	"go.uber.org/zap"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type ModuleHandler struct {
@@ -192,6 +195,39 @@ type CreateModuleRequest struct {
 	Hibernated   bool               `json:"hibernated"`
 }
 
+// SourceErrors reports a request that sets neither or both of helm and custom, keyed like validation errors.
+func (req *CreateModuleRequest) SourceErrors() map[string]string {
+	if req.Helm == nil && req.Custom == nil {
+		return map[string]string{
+			"CreateModuleRequest": "Either 'helm' or 'custom' must be provided.",
+		}
+	}
+
+	if req.Helm != nil && req.Custom != nil {
+		return map[string]string{
+			"CreateModuleRequest": "Only one of 'helm' or 'custom' can be provided, not both.",
+		}
+	}
+
+	return nil
+}
+
+func (req *CreateModuleRequest) ModuleCreateIn() forkspacer.ModuleCreateIn {
+	return forkspacer.ModuleCreateIn{
+		Name:      req.Name,
+		Namespace: req.Namespace,
+		Workspace: forkspacer.ResourceReference{
+			Name:      req.Workspace.Name,
+			Namespace: req.Workspace.Namespace,
+		},
+		Helm:         convertHelmRequestToCRD(req.Helm),
+		Custom:       convertCustomRequestToCRD(req.Custom),
+		Config:       req.Config,
+		ConfigSchema: convertConfigSchemaRequestToCRD(req.ConfigSchema),
+		Hibernated:   req.Hibernated,
+	}
+}
+
 type ModuleResponse struct {
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
@@ -413,70 +449,34 @@ func convertConfigSchemaRequestToCRD(items []ConfigItem) []batchv1.ConfigItem {
 }
 
 func (h ModuleHandler) CreateHandle(w http.ResponseWriter, r *http.Request) {
-	var requestData = &CreateModuleRequest{}
-	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
+	dryRun, err := ReadDryRun(w, r)
+	if err != nil {
 		return
 	}
 
-	// Validate that either Helm or Custom is provided, but not both
-	if requestData.Helm == nil && requestData.Custom == nil {
-		response.JSONBodyValidationError(w, map[string]string{
-			"CreateModuleRequest": "Either 'helm' or 'custom' must be provided.",
-		})
+	var requestData = &CreateModuleRequest{}
+	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
 		return
 	}
 
-	if requestData.Helm != nil && requestData.Custom != nil {
-		response.JSONBodyValidationError(w, map[string]string{
-			"CreateModuleRequest": "Only one of 'helm' or 'custom' can be provided, not both.",
-		})
+	if errs := requestData.SourceErrors(); errs != nil {
+		response.JSONBodyValidationError(w, errs)
 		return
 	}
 
-	// Convert handler types to CRD types
-	var helmSpec *batchv1.ModuleSpecHelm
-	var customSpec *batchv1.ModuleSpecCustom
-	var configSchema []batchv1.ConfigItem
-
-	if requestData.Helm != nil {
-		helmSpec = convertHelmRequestToCRD(requestData.Helm)
-	}
-
-	if requestData.Custom != nil {
-		customSpec = convertCustomRequestToCRD(requestData.Custom)
-	}
-
-	if requestData.ConfigSchema != nil {
-		configSchema = convertConfigSchemaRequestToCRD(requestData.ConfigSchema)
-	}
-
-	module, err := h.forkspacerModuleService.Create(r.Context(), forkspacer.ModuleCreateIn{
-		Name:      requestData.Name,
-		Namespace: requestData.Namespace,
-		Workspace: forkspacer.ResourceReference{
-			Name:      requestData.Workspace.Name,
-			Namespace: requestData.Workspace.Namespace,
-		},
-		Helm:         helmSpec,
-		Custom:       customSpec,
-		Config:       requestData.Config,
-		ConfigSchema: configSchema,
-		Hibernated:   requestData.Hibernated,
-	})
+	module, err := h.forkspacerModuleService.Create(
+		r.Context(), requestData.ModuleCreateIn(), DryRunOptions[client.CreateOption](dryRun)...,
+	)
 	if err != nil {
-		response.JSONBadRequest(w, err.Error())
+		ServiceError(w, r, h.logger, "failed to create module", err)
 		return
 	}
 
-	response.JSONSuccess(w, 201,
-		response.NewJSONSuccess(
-			response.SuccessCodes.Created,
-			ModuleResponse{
-				Name:      module.Name,
-				Namespace: module.Namespace,
-			},
-		),
-	)
+	var data any = ModuleResponse{Name: module.Name, Namespace: module.Namespace}
+	if dryRun {
+		data = DryRunObject(module, "Module")
+	}
+	response.JSONSuccess(w, 201, response.NewJSONSuccess(response.SuccessCodes.Created, data))
 }
 
 type UpdateModuleRequest struct {
@@ -486,6 +486,11 @@ type UpdateModuleRequest struct {
 }
 
 func (h ModuleHandler) UpdateHandle(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
 	var requestData = &UpdateModuleRequest{}
 	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
 		return
@@ -497,21 +502,17 @@ func (h ModuleHandler) UpdateHandle(w http.ResponseWriter, r *http.Request) {
 		Hibernated: requestData.Hibernated,
 	}
 
-	module, err := h.forkspacerModuleService.Update(r.Context(), updateIn)
+	module, err := h.forkspacerModuleService.Update(r.Context(), updateIn, DryRunOptions[client.UpdateOption](dryRun)...)
 	if err != nil {
-		response.JSONBadRequest(w, err.Error())
+		ServiceError(w, r, h.logger, "failed to update module", err)
 		return
 	}
 
-	response.JSONSuccess(w, 200,
-		response.NewJSONSuccess(
-			response.SuccessCodes.Ok,
-			ModuleResponse{
-				Name:      module.Name,
-				Namespace: module.Namespace,
-			},
-		),
-	)
+	var data any = ModuleResponse{Name: module.Name, Namespace: module.Namespace}
+	if dryRun {
+		data = DryRunObject(module, "Module")
+	}
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, data))
 }
 
 type DeleteModuleRequest struct {
@@ -520,13 +521,20 @@ type DeleteModuleRequest struct {
 }
 
 func (h ModuleHandler) DeleteHandle(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
 	var requestData = &DeleteModuleRequest{}
 	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
 		return
 	}
 
-	if err := h.forkspacerModuleService.Delete(r.Context(), requestData.Name, requestData.Namespace); err != nil {
-		response.JSONBadRequest(w, err.Error())
+	if err := h.forkspacerModuleService.Delete(
+		r.Context(), requestData.Name, requestData.Namespace, DryRunOptions[client.DeleteOption](dryRun)...,
+	); err != nil {
+		ServiceError(w, r, h.logger, "failed to delete module", err)
 		return
 	}
 
@@ -534,23 +542,29 @@ func (h ModuleHandler) DeleteHandle(w http.ResponseWriter, r *http.Request) {
 }
 
 type ListModulesRequestQuery struct {
+	Namespace     *string `json:"namespace,omitempty" validate:"omitempty,dns1123label"`
 	Limit         *int64  `json:"limit,omitempty" validate:"omitempty,gte=1,lte=250"`
 	ContinueToken *string `json:"continueToken,omitempty"`
 }
 
 type ModuleListItem struct {
-	Name       string              `json:"name"`
-	Namespace  string              `json:"namespace"`
-	Phase      string              `json:"phase"`
-	Message    string              `json:"message"`
-	Hibernated bool                `json:"hibernated"`
-	Type       string              `json:"type"`
-	Workspace  *WorkspaceReference `json:"workspace,omitempty"`
+	Name            string              `json:"name"`
+	Namespace       string              `json:"namespace"`
+	Phase           string              `json:"phase"`
+	Message         string              `json:"message"`
+	Hibernated      bool                `json:"hibernated"`
+	Type            string              `json:"type"`
+	Workspace       *WorkspaceReference `json:"workspace,omitempty"`
+	CreatedAt       time.Time           `json:"createdAt,omitzero"`
+	Age             string              `json:"age,omitempty"`
+	Labels          map[string]string   `json:"labels,omitempty"`
+	StatusUpdatedAt time.Time           `json:"statusUpdatedAt,omitzero"`
 }
 
 type ListModulesResponse struct {
-	ContinueToken string           `json:"continueToken"`
-	Modules       []ModuleListItem `json:"modules"`
+	ContinueToken string `json:"continueToken"`
+	// Modules holds ModuleListItems, restricted to the requested fields if any
+	Modules any `json:"modules"`
 }
 
 func (h ModuleHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
@@ -569,6 +583,10 @@ func (h ModuleHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
 		requestData.ContinueToken = utils.ToPtr(r.URL.Query().Get("continueToken"))
 	}
 
+	if r.URL.Query().Has("namespace") {
+		requestData.Namespace = utils.ToPtr(r.URL.Query().Get("namespace"))
+	}
+
 	if err := validation.URLParamsValidate(r.Context(), w, requestData); err != nil {
 		return
 	}
@@ -577,21 +595,29 @@ func (h ModuleHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
 		requestData.Limit = utils.ToPtr[int64](25)
 	}
 
+	sort, err := ReadListSort(w, r)
+	if err != nil {
+		return
+	}
+
+	fields, err := ReadListFields[ModuleListItem](w, r)
+	if err != nil {
+		return
+	}
+
 	moduleList, err := h.forkspacerModuleService.List(
 		r.Context(),
+		requestData.Namespace,
 		*requestData.Limit,
 		requestData.ContinueToken,
+		sort,
 	)
 	if err != nil {
-		response.JSONBadRequest(w, err.Error())
+		ServiceError(w, r, h.logger, "failed to list modules", err)
 		return
 	}
 
-	responseData := ListModulesResponse{
-		ContinueToken: moduleList.Continue,
-		Modules:       make([]ModuleListItem, len(moduleList.Items)),
-	}
-
+	items := make([]ModuleListItem, len(moduleList.Items))
 	for i, module := range moduleList.Items {
 		hibernated := module.Spec.Hibernated
 
@@ -600,7 +626,7 @@ func (h ModuleHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
 			message = *module.Status.Message
 		}
 
-		responseData.Modules[i] = ModuleListItem{
+		items[i] = ModuleListItem{
 			Name:       module.Name,
 			Namespace:  module.Namespace,
 			Phase:      string(module.Status.Phase),
@@ -611,9 +637,25 @@ func (h ModuleHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
 				Name:      module.Spec.Workspace.Name,
 				Namespace: module.Spec.Workspace.Namespace,
 			},
+			CreatedAt:       module.CreationTimestamp.Time,
+			Age:             ObjectAge(&module),
+			Labels:          module.Labels,
+			StatusUpdatedAt: StatusUpdatedAt(&module),
 		}
 	}
 
+	projected, err := ProjectFields(items, fields)
+	if err != nil {
+		logging.FromContext(r.Context(), h.logger).Error("failed to project module fields", zap.Error(err))
+		response.JSONInternal(w)
+		return
+	}
+
+	responseData := ListModulesResponse{
+		ContinueToken: moduleList.Continue,
+		Modules:       projected,
+	}
+
 	response.JSONSuccess(w, 200,
 		response.NewJSONSuccess(
 			response.SuccessCodes.Ok,
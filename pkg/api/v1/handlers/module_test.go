@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/forkspacer/api-server/pkg/services/forkspacer/forkspacertest"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestModuleHandlers(t *testing.T) {
+	const newModule = `{"name":"postgres","workspace":{"name":"dev","namespace":"default"},` +
+		`"custom":{"image":"postgres:18"}}`
+
+	tests := []handlerTest{
+		{
+			name:       "create",
+			method:     "POST",
+			target:     "/module",
+			body:       newModule,
+			wantStatus: 201,
+			wantCode:   "created",
+			check: func(t *testing.T, kubeClient client.Client, data any) {
+				if forkspacertest.Member(data, "name") != "postgres" || forkspacertest.Member(data, "namespace") != "default" {
+					t.Errorf("data = %v, want the postgres module of the default namespace", data)
+				}
+				if !forkspacertest.GetObject(t, kubeClient, &batchv1.Module{}, "default", "postgres") {
+					t.Error("module was not created")
+				}
+			},
+		},
+		{
+			name:       "create dry run",
+			method:     "POST",
+			target:     "/module?dryRun=true",
+			body:       newModule,
+			wantStatus: 201,
+			wantCode:   "created",
+			check: func(t *testing.T, kubeClient client.Client, data any) {
+				if forkspacertest.Member(data, "kind") != "Module" ||
+					forkspacertest.Member(data, "spec", "workspace", "name") != "dev" {
+					t.Errorf("data = %v, want the module object", data)
+				}
+				if forkspacertest.GetObject(t, kubeClient, &batchv1.Module{}, "default", "postgres") {
+					t.Error("dry run created the module")
+				}
+			},
+		},
+		{
+			name:       "neither helm nor custom",
+			method:     "POST",
+			target:     "/module",
+			body:       `{"name":"postgres","workspace":{"name":"dev","namespace":"default"}}`,
+			wantStatus: 400,
+			wantCode:   "body_validation",
+		},
+		{
+			name:   "helm chart without a namespace",
+			method: "POST",
+			target: "/module",
+			body: `{"name":"postgres","workspace":{"name":"dev","namespace":"default"},` +
+				`"helm":{"chart":{"repo":{"url":"https://charts.example.com","chart":"postgres"}}}}`,
+			wantStatus: 400,
+			wantCode:   "body_validation",
+		},
+		{
+			name:   "chart in a namespace outside the policy",
+			method: "POST",
+			target: "/module",
+			body: `{"name":"postgres","workspace":{"name":"dev","namespace":"default"},` +
+				`"helm":{"namespace":"db","chart":{"configMap":{"name":"chart","namespace":"kube-system","key":"chart.tgz"}}}}`,
+			wantStatus: 403,
+			wantCode:   "forbidden",
+		},
+		{
+			name:       "already exists",
+			method:     "POST",
+			target:     "/module",
+			body:       `{"name":"redis","workspace":{"name":"dev","namespace":"default"},"custom":{"image":"redis:8"}}`,
+			wantStatus: 409,
+			wantCode:   "conflict",
+		},
+		{
+			name:       "update",
+			method:     "PATCH",
+			target:     "/module",
+			body:       `{"name":"cache","namespace":"team-a","hibernated":true}`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, kubeClient client.Client, _ any) {
+				module := &batchv1.Module{}
+				if forkspacertest.GetObject(t, kubeClient, module, "team-a", "cache"); !module.Spec.Hibernated {
+					t.Error("module was not hibernated")
+				}
+			},
+		},
+		{
+			name:       "update dry run",
+			method:     "PATCH",
+			target:     "/module?dryRun=true",
+			body:       `{"name":"redis","hibernated":true}`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, kubeClient client.Client, data any) {
+				if forkspacertest.Member(data, "kind") != "Module" || forkspacertest.Member(data, "spec", "hibernated") != true {
+					t.Errorf("data = %v, want the updated module", data)
+				}
+				module := &batchv1.Module{}
+				if forkspacertest.GetObject(t, kubeClient, module, "default", "redis"); module.Spec.Hibernated {
+					t.Error("dry run hibernated the module")
+				}
+			},
+		},
+		{
+			name:       "update missing",
+			method:     "PATCH",
+			target:     "/module",
+			body:       `{"name":"postgres","hibernated":true}`,
+			wantStatus: 404,
+			wantCode:   "not_found",
+		},
+		{
+			name:       "update in a namespace outside the policy",
+			method:     "PATCH",
+			target:     "/module",
+			body:       `{"name":"redis","namespace":"kube-system","hibernated":true}`,
+			wantStatus: 403,
+			wantCode:   "forbidden",
+		},
+		{
+			name:       "delete",
+			method:     "DELETE",
+			target:     "/module",
+			body:       `{"name":"redis"}`,
+			wantStatus: 204,
+			check: func(t *testing.T, kubeClient client.Client, _ any) {
+				if forkspacertest.GetObject(t, kubeClient, &batchv1.Module{}, "default", "redis") {
+					t.Error("module was not deleted")
+				}
+			},
+		},
+		{
+			name:       "delete dry run",
+			method:     "DELETE",
+			target:     "/module?dryRun=true",
+			body:       `{"name":"redis"}`,
+			wantStatus: 204,
+			check: func(t *testing.T, kubeClient client.Client, _ any) {
+				if !forkspacertest.GetObject(t, kubeClient, &batchv1.Module{}, "default", "redis") {
+					t.Error("dry run deleted the module")
+				}
+			},
+		},
+		{
+			name:       "delete missing",
+			method:     "DELETE",
+			target:     "/module",
+			body:       `{"name":"postgres"}`,
+			wantStatus: 404,
+			wantCode:   "not_found",
+		},
+		{
+			name:       "list",
+			method:     "GET",
+			target:     "/module/list?namespace=team-a",
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, _ client.Client, data any) {
+				modules, _ := forkspacertest.Member(data, "modules").([]any)
+				if len(modules) != 1 || forkspacertest.Member(modules[0], "name") != "cache" {
+					t.Errorf("modules = %v, want the cache module", modules)
+				}
+			},
+		},
+		{
+			name:       "invalid limit",
+			method:     "GET",
+			target:     "/module/list?limit=0",
+			wantStatus: 400,
+			wantCode:   "query_validation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, forkspacertest.Objects()...)
+		})
+	}
+}
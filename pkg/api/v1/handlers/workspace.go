@@ -1,69 +1,68 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/forkspacer/api-server/pkg/api/response"
 	"github.com/forkspacer/api-server/pkg/api/validation"
+	"github.com/forkspacer/api-server/pkg/logging"
 	"github.com/forkspacer/api-server/pkg/services/forkspacer"
 	"github.com/forkspacer/api-server/pkg/utils"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
 	"go.uber.org/zap"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type WorkspaceHandler struct {
 	logger                     *zap.Logger
 	forkspacerWorkspaceService *forkspacer.ForkspacerWorkspaceService
+	forkspacerModuleService    *forkspacer.ForkspacerModuleService
+	strictKubeconfig           bool
 }
 
 func NewWorkspaceHandler(
 	logger *zap.Logger,
 	forkspacerWorkspaceService *forkspacer.ForkspacerWorkspaceService,
+	forkspacerModuleService *forkspacer.ForkspacerModuleService,
+	strictKubeconfig bool,
 ) *WorkspaceHandler {
-	return &WorkspaceHandler{logger, forkspacerWorkspaceService}
+	return &WorkspaceHandler{logger, forkspacerWorkspaceService, forkspacerModuleService, strictKubeconfig}
 }
 
 type CreateKubeconfigSecretRequest struct {
-	Name       string `json:"name" validate:"required,dns1123subdomain"`
-	Kubeconfig []byte `json:"kubeconfig" validate:"required,kubeconfig"`
+	Name       string  `json:"name" validate:"required,dns1123subdomain"`
+	Namespace  *string `json:"namespace,omitempty" validate:"omitempty,dns1123label"`
+	Kubeconfig []byte  `json:"kubeconfig" validate:"required,kubeconfig"`
 }
 
+// KubeconfigUploadMaxBytes caps kubeconfig uploads. The router applies it as the route's body limit.
+const KubeconfigUploadMaxBytes int64 = 10 << 20
+
 type KubeconfigSecretResponse struct {
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
 }
 
 func (h WorkspaceHandler) CreateKubeconfigSecretHandle(w http.ResponseWriter, r *http.Request) {
-	var requestData = &CreateKubeconfigSecretRequest{}
-
-	// upload of 10 MB.
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		response.JSONFormDataTooLarge(w, utils.ToPtr[int64](10<<20))
-		return
-	}
-	requestData.Name = r.PostFormValue("name")
-
-	file, _, err := r.FormFile("kubeconfig")
+	dryRun, err := ReadDryRun(w, r)
 	if err != nil {
-		response.JSONBadRequest(w, "Kubeconfig file is required")
 		return
 	}
-	defer func() { _ = file.Close() }()
 
-	requestData.Kubeconfig, err = io.ReadAll(file)
+	requestData, err := ReadKubeconfigSecretRequest(w, r, h.logger, h.strictKubeconfig)
 	if err != nil {
-		response.JSONBadRequest(w, "Invalid kubeconfig file content: "+err.Error())
-		return
-	}
-
-	if err := validation.FormDataBodyValidate(w, r, requestData); err != nil {
 		return
 	}
 
 	if secret, err := h.forkspacerWorkspaceService.CreateKubeconfigSecret(
-		r.Context(), requestData.Name, nil, requestData.Kubeconfig,
+		r.Context(), requestData.Name, requestData.Namespace, requestData.Kubeconfig,
+		DryRunOptions[client.CreateOption](dryRun)...,
 	); err != nil {
-		response.JSONBadRequest(w, err.Error())
+		ServiceError(w, r, h.logger, "failed to create kubeconfig secret", err)
 		return
 	} else {
 		response.JSONSuccess(w, 201,
@@ -79,20 +78,88 @@ func (h WorkspaceHandler) CreateKubeconfigSecretHandle(w http.ResponseWriter, r
 	}
 }
 
+// ReadKubeconfigSecretRequest parses and validates a multipart kubeconfig upload, writing the error
+// response itself when the request is invalid. In strict mode the kubeconfig is sanitized as well.
+func ReadKubeconfigSecretRequest(
+	w http.ResponseWriter, r *http.Request, logger *zap.Logger, strict bool,
+) (*CreateKubeconfigSecretRequest, error) {
+	var requestData = &CreateKubeconfigSecretRequest{}
+
+	if err := r.ParseMultipartForm(KubeconfigUploadMaxBytes); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			response.JSONFormDataTooLarge(w, &maxBytesErr.Limit)
+		case errors.Is(err, http.ErrNotMultipart):
+			response.JSONUnsopportedMediaType(w, "multipart/form-data")
+		default:
+			response.JSONBadRequest(w, "Invalid form data: "+err.Error())
+		}
+		return nil, fmt.Errorf("failed to parse form data: %w", err)
+	}
+	requestData.Name = r.PostFormValue("name")
+	if r.PostForm.Has("namespace") {
+		requestData.Namespace = utils.ToPtr(r.PostFormValue("namespace"))
+	}
+
+	file, _, err := r.FormFile("kubeconfig")
+	if err != nil {
+		response.JSONBadRequest(w, "Kubeconfig file is required")
+		return nil, fmt.Errorf("failed to read kubeconfig file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	requestData.Kubeconfig, err = io.ReadAll(file)
+	if err != nil {
+		response.JSONBadRequest(w, "Invalid kubeconfig file content: "+err.Error())
+		return nil, fmt.Errorf("failed to read kubeconfig file: %w", err)
+	}
+
+	if err := validation.FormDataBodyValidate(w, r, requestData); err != nil {
+		return nil, err
+	}
+
+	if strict {
+		sanitizedKubeconfig, err := validation.SanitizeKubeconfig(requestData.Kubeconfig)
+		if err != nil {
+			var kubeconfigErr *validation.KubeconfigError
+			if !errors.As(err, &kubeconfigErr) {
+				logging.FromContext(r.Context(), logger).Error("failed to sanitize kubeconfig", zap.Error(err))
+				response.JSONInternal(w)
+				return nil, err
+			}
+
+			response.JSONBodyValidationError(w, map[string]string{
+				"kubeconfig": kubeconfigErr.Error(),
+			})
+			return nil, err
+		}
+		requestData.Kubeconfig = sanitizedKubeconfig
+	}
+
+	return requestData, nil
+}
+
 type DeleteKubeconfigSecretRequest struct {
-	Name string `json:"name" validate:"required,dns1123subdomain"`
+	Name      string  `json:"name" validate:"required,dns1123subdomain"`
+	Namespace *string `json:"namespace,omitempty" validate:"omitempty,dns1123label"`
 }
 
 func (h WorkspaceHandler) DeleteKubeconfigSecretHandle(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
 	var requestData = &DeleteKubeconfigSecretRequest{}
 	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
 		return
 	}
 
 	if err := h.forkspacerWorkspaceService.DeleteKubeconfigSecret(
-		r.Context(), requestData.Name, nil,
+		r.Context(), requestData.Name, requestData.Namespace, DryRunOptions[client.DeleteOption](dryRun)...,
 	); err != nil {
-		response.JSONBadRequest(w, err.Error())
+		ServiceError(w, r, h.logger, "failed to delete kubeconfig secret", err)
 		return
 	} else {
 		response.JSONDeleted(w)
@@ -101,6 +168,7 @@ func (h WorkspaceHandler) DeleteKubeconfigSecretHandle(w http.ResponseWriter, r
 }
 
 type ListKubeconfigSecretsRequestQuery struct {
+	Namespace     *string `json:"namespace" validate:"omitempty,dns1123label"`
 	Limit         *int64  `json:"limit" validate:"omitempty,gte=1,lte=250"`
 	ContinueToken *string `json:"continueToken"`
 }
@@ -126,6 +194,10 @@ func (h WorkspaceHandler) ListKubeconfigSecretsHandle(w http.ResponseWriter, r *
 		requestData.ContinueToken = utils.ToPtr(r.URL.Query().Get("continueToken"))
 	}
 
+	if r.URL.Query().Has("namespace") {
+		requestData.Namespace = utils.ToPtr(r.URL.Query().Get("namespace"))
+	}
+
 	if err := validation.URLParamsValidate(r.Context(), w, requestData); err != nil {
 		return
 	}
@@ -135,9 +207,9 @@ func (h WorkspaceHandler) ListKubeconfigSecretsHandle(w http.ResponseWriter, r *
 	}
 
 	if secrets, err := h.forkspacerWorkspaceService.ListKubeconfigSecrets(
-		r.Context(), *requestData.Limit, requestData.ContinueToken,
+		r.Context(), requestData.Namespace, *requestData.Limit, requestData.ContinueToken,
 	); err != nil {
-		response.JSONBadRequest(w, err.Error())
+		ServiceError(w, r, h.logger, "failed to list kubeconfig secrets", err)
 		return
 	} else {
 		responseData := ListKubeconfigSecretsResponse{
@@ -194,74 +266,95 @@ type CreateWorkspaceRequest struct {
 	AutoHibernation *WorkspaceAutoHibernation   `json:"autoHibernation,omitempty"`
 }
 
-type WorkspaceResponse struct {
-	Name      string `json:"name"`
-	Namespace string `json:"namespace"`
+// WorkspaceAutoHibernationIn converts the request to its service input. It returns nil for a nil request.
+func (a *WorkspaceAutoHibernation) WorkspaceAutoHibernationIn() *forkspacer.WorkspaceAutoHibernationIn {
+	if a == nil {
+		return nil
+	}
+
+	return &forkspacer.WorkspaceAutoHibernationIn{
+		Enabled:      a.Enabled,
+		Schedule:     a.Schedule,
+		WakeSchedule: a.WakeSchedule,
+	}
 }
 
-func (h WorkspaceHandler) CreateHandle(w http.ResponseWriter, r *http.Request) {
-	var requestData = &CreateWorkspaceRequest{}
-	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
-		return
+// ManagedClusterIn converts the request to its service input. It returns nil for a nil request.
+func (m *ManagedCluster) ManagedClusterIn() *forkspacer.ManagedClusterIn {
+	if m == nil {
+		return nil
+	}
+
+	return &forkspacer.ManagedClusterIn{
+		Backend: m.Backend,
+		Distro:  m.Distro,
 	}
+}
 
+func (req *CreateWorkspaceRequest) WorkspaceCreateIn() forkspacer.WorkspaceCreateIn {
 	workspaceIn := forkspacer.WorkspaceCreateIn{
-		Name:       requestData.Name,
-		Namespace:  requestData.Namespace,
-		Type:       requestData.Type,
-		Hibernated: requestData.Hibernated,
+		Name:            req.Name,
+		Namespace:       req.Namespace,
+		Type:            req.Type,
+		Hibernated:      req.Hibernated,
+		ManagedCluster:  req.ManagedCluster.ManagedClusterIn(),
+		AutoHibernation: req.AutoHibernation.WorkspaceAutoHibernationIn(),
 	}
 
-	if requestData.From != nil {
+	if req.From != nil {
 		workspaceIn.From = &forkspacer.ResourceReference{
-			Name:      requestData.From.Name,
-			Namespace: requestData.From.Namespace,
+			Name:      req.From.Name,
+			Namespace: req.From.Namespace,
 		}
 	}
 
-	if requestData.Connection != nil {
+	if req.Connection != nil {
 		workspaceIn.Connection = &forkspacer.WorkspaceCreateConnectionIn{
-			Type: requestData.Connection.Type,
-			Key:  requestData.Connection.Key,
+			Type: req.Connection.Type,
+			Key:  req.Connection.Key,
 		}
-		if requestData.Connection.Secret != nil {
+		if req.Connection.Secret != nil {
 			workspaceIn.Connection.Secret = &forkspacer.ResourceReference{
-				Name:      requestData.Connection.Secret.Name,
-				Namespace: requestData.Connection.Secret.Namespace,
+				Name:      req.Connection.Secret.Name,
+				Namespace: req.Connection.Secret.Namespace,
 			}
 		}
 	}
 
-	if requestData.ManagedCluster != nil {
-		workspaceIn.ManagedCluster = &forkspacer.ManagedClusterIn{
-			Backend: requestData.ManagedCluster.Backend,
-			Distro:  requestData.ManagedCluster.Distro,
-		}
+	return workspaceIn
+}
+
+type WorkspaceResponse struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+func (h WorkspaceHandler) CreateHandle(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := ReadDryRun(w, r)
+	if err != nil {
+		return
 	}
 
-	if requestData.AutoHibernation != nil {
-		workspaceIn.AutoHibernation = &forkspacer.WorkspaceAutoHibernationIn{
-			Enabled:      requestData.AutoHibernation.Enabled,
-			Schedule:     requestData.AutoHibernation.Schedule,
-			WakeSchedule: requestData.AutoHibernation.WakeSchedule,
-		}
+	var requestData = &CreateWorkspaceRequest{}
+	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
+		return
 	}
 
-	workspace, err := h.forkspacerWorkspaceService.Create(r.Context(), workspaceIn)
+	workspaceIn := requestData.WorkspaceCreateIn()
+
+	workspace, err := h.forkspacerWorkspaceService.Create(
+		r.Context(), workspaceIn, DryRunOptions[client.CreateOption](dryRun)...,
+	)
 	if err != nil {
-		response.JSONBadRequest(w, err.Error())
+		ServiceError(w, r, h.logger, "failed to create workspace", err)
 		return
 	}
 
-	response.JSONSuccess(w, 201,
-		response.NewJSONSuccess(
-			response.SuccessCodes.Created,
-			WorkspaceResponse{
-				Name:      workspace.Name,
-				Namespace: workspace.Namespace,
-			},
-		),
-	)
+	var data any = WorkspaceResponse{Name: workspace.Name, Namespace: workspace.Namespace}
+	if dryRun {
+		data = DryRunObject(workspace, "Workspace")
+	}
+	response.JSONSuccess(w, 201, response.NewJSONSuccess(response.SuccessCodes.Created, data))
 }
 
 type UpdateWorkspaceRequest struct {
@@ -273,47 +366,37 @@ type UpdateWorkspaceRequest struct {
 }
 
 func (h WorkspaceHandler) UpdateHandle(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
 	var requestData = &UpdateWorkspaceRequest{}
 	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
 		return
 	}
 
 	updateIn := forkspacer.WorkspaceUpdateIn{
-		Name:       requestData.Name,
-		Namespace:  requestData.Namespace,
-		Hibernated: requestData.Hibernated,
+		Name:            requestData.Name,
+		Namespace:       requestData.Namespace,
+		Hibernated:      requestData.Hibernated,
+		AutoHibernation: requestData.AutoHibernation.WorkspaceAutoHibernationIn(),
+		ManagedCluster:  requestData.ManagedCluster.ManagedClusterIn(),
 	}
 
-	if requestData.AutoHibernation != nil {
-		updateIn.AutoHibernation = &forkspacer.WorkspaceAutoHibernationIn{
-			Enabled:      requestData.AutoHibernation.Enabled,
-			Schedule:     requestData.AutoHibernation.Schedule,
-			WakeSchedule: requestData.AutoHibernation.WakeSchedule,
-		}
-	}
-
-	if requestData.ManagedCluster != nil {
-		updateIn.ManagedCluster = &forkspacer.ManagedClusterIn{
-			Backend: requestData.ManagedCluster.Backend,
-			Distro:  requestData.ManagedCluster.Distro,
-		}
-	}
-
-	workspace, err := h.forkspacerWorkspaceService.Update(r.Context(), updateIn)
+	workspace, err := h.forkspacerWorkspaceService.Update(
+		r.Context(), updateIn, DryRunOptions[client.UpdateOption](dryRun)...,
+	)
 	if err != nil {
-		response.JSONBadRequest(w, err.Error())
+		ServiceError(w, r, h.logger, "failed to update workspace", err)
 		return
 	}
 
-	response.JSONSuccess(w, 200,
-		response.NewJSONSuccess(
-			response.SuccessCodes.Ok,
-			WorkspaceResponse{
-				Name:      workspace.Name,
-				Namespace: workspace.Namespace,
-			},
-		),
-	)
+	var data any = WorkspaceResponse{Name: workspace.Name, Namespace: workspace.Namespace}
+	if dryRun {
+		data = DryRunObject(workspace, "Workspace")
+	}
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, data))
 }
 
 type DeleteWorkspaceRequest struct {
@@ -322,13 +405,20 @@ type DeleteWorkspaceRequest struct {
 }
 
 func (h WorkspaceHandler) DeleteHandle(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
 	var requestData = &DeleteWorkspaceRequest{}
 	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
 		return
 	}
 
-	if err := h.forkspacerWorkspaceService.Delete(r.Context(), requestData.Name, requestData.Namespace); err != nil {
-		response.JSONBadRequest(w, err.Error())
+	if err := h.forkspacerWorkspaceService.Delete(
+		r.Context(), requestData.Name, requestData.Namespace, DryRunOptions[client.DeleteOption](dryRun)...,
+	); err != nil {
+		ServiceError(w, r, h.logger, "failed to delete workspace", err)
 		return
 	}
 
@@ -336,22 +426,40 @@ func (h WorkspaceHandler) DeleteHandle(w http.ResponseWriter, r *http.Request) {
 }
 
 type ListWorkspacesRequestQuery struct {
+	Namespace     *string `json:"namespace,omitempty" validate:"omitempty,dns1123label"`
 	Limit         *int64  `json:"limit,omitempty" validate:"omitempty,gte=1,lte=250"`
 	ContinueToken *string `json:"continueToken,omitempty"`
 }
 
 type WorkspaceListItem struct {
-	Name       string `json:"name"`
-	Namespace  string `json:"namespace"`
-	Phase      string `json:"phase"`
-	Message    string `json:"message"`
-	Type       string `json:"type"`
-	Hibernated bool   `json:"hibernated"`
+	Name                  string                    `json:"name"`
+	Namespace             string                    `json:"namespace"`
+	Phase                 string                    `json:"phase"`
+	Message               string                    `json:"message"`
+	Type                  string                    `json:"type"`
+	Hibernated            bool                      `json:"hibernated"`
+	CreatedAt             time.Time                 `json:"createdAt,omitzero"`
+	Age                   string                    `json:"age,omitempty"`
+	Labels                map[string]string         `json:"labels,omitempty"`
+	ManagedClusterBackend string                    `json:"managedClusterBackend,omitempty"`
+	AutoHibernation       *WorkspaceAutoHibernation `json:"autoHibernation,omitempty"`
+	ModuleCount           int                       `json:"moduleCount"`
+	StatusUpdatedAt       time.Time                 `json:"statusUpdatedAt,omitzero"`
+}
+
+// WorkspaceReferences returns the references of the workspaces of a list, in order.
+func WorkspaceReferences(workspaceList *batchv1.WorkspaceList) []forkspacer.ResourceReference {
+	references := make([]forkspacer.ResourceReference, len(workspaceList.Items))
+	for i, workspace := range workspaceList.Items {
+		references[i] = forkspacer.ResourceReference{Name: workspace.Name, Namespace: workspace.Namespace}
+	}
+	return references
 }
 
 type ListWorkspacesResponse struct {
-	ContinueToken string              `json:"continueToken"`
-	Workspaces    []WorkspaceListItem `json:"workspaces"`
+	ContinueToken string `json:"continueToken"`
+	// Workspaces holds WorkspaceListItems, restricted to the requested fields if any
+	Workspaces any `json:"workspaces"`
 }
 
 func (h WorkspaceHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
@@ -370,6 +478,10 @@ func (h WorkspaceHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
 		requestData.ContinueToken = utils.ToPtr(r.URL.Query().Get("continueToken"))
 	}
 
+	if r.URL.Query().Has("namespace") {
+		requestData.Namespace = utils.ToPtr(r.URL.Query().Get("namespace"))
+	}
+
 	if err := validation.URLParamsValidate(r.Context(), w, requestData); err != nil {
 		return
 	}
@@ -378,21 +490,38 @@ func (h WorkspaceHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
 		requestData.Limit = utils.ToPtr[int64](25)
 	}
 
+	sort, err := ReadListSort(w, r)
+	if err != nil {
+		return
+	}
+
+	fields, err := ReadListFields[WorkspaceListItem](w, r)
+	if err != nil {
+		return
+	}
+
 	workspaceList, err := h.forkspacerWorkspaceService.List(
 		r.Context(),
+		requestData.Namespace,
 		*requestData.Limit,
 		requestData.ContinueToken,
+		sort,
 	)
 	if err != nil {
-		response.JSONBadRequest(w, err.Error())
+		ServiceError(w, r, h.logger, "failed to list workspaces", err)
 		return
 	}
 
-	responseData := ListWorkspacesResponse{
-		ContinueToken: workspaceList.Continue,
-		Workspaces:    make([]WorkspaceListItem, len(workspaceList.Items)),
+	var moduleCounts map[forkspacer.ResourceReference]int
+	if IncludesField(fields, "moduleCount") {
+		moduleCounts, err = h.forkspacerModuleService.CountByWorkspace(r.Context(), WorkspaceReferences(workspaceList))
+		if err != nil {
+			ServiceError(w, r, h.logger, "failed to count workspace modules", err)
+			return
+		}
 	}
 
+	items := make([]WorkspaceListItem, len(workspaceList.Items))
 	for i, workspace := range workspaceList.Items {
 		hibernated := workspace.Spec.Hibernated
 
@@ -400,16 +529,43 @@ func (h WorkspaceHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
 			workspace.Status.Message = utils.ToPtr("")
 		}
 
-		responseData.Workspaces[i] = WorkspaceListItem{
+		items[i] = WorkspaceListItem{
 			Name:       workspace.Name,
 			Namespace:  workspace.Namespace,
 			Phase:      string(workspace.Status.Phase),
 			Type:       string(workspace.Spec.Type),
 			Hibernated: hibernated,
 			Message:    *workspace.Status.Message,
+			CreatedAt:  workspace.CreationTimestamp.Time,
+			Age:        ObjectAge(&workspace),
+			Labels:     workspace.Labels,
+			AutoHibernation: &WorkspaceAutoHibernation{
+				Enabled:      workspace.Spec.AutoHibernation.Enabled,
+				Schedule:     workspace.Spec.AutoHibernation.Schedule,
+				WakeSchedule: workspace.Spec.AutoHibernation.WakeSchedule,
+			},
+			ModuleCount: moduleCounts[forkspacer.ResourceReference{
+				Name: workspace.Name, Namespace: workspace.Namespace,
+			}],
+			StatusUpdatedAt: StatusUpdatedAt(&workspace),
+		}
+		if workspace.Spec.ManagedCluster != nil {
+			items[i].ManagedClusterBackend = string(workspace.Spec.ManagedCluster.Backend)
 		}
 	}
 
+	projected, err := ProjectFields(items, fields)
+	if err != nil {
+		logging.FromContext(r.Context(), h.logger).Error("failed to project workspace fields", zap.Error(err))
+		response.JSONInternal(w)
+		return
+	}
+
+	responseData := ListWorkspacesResponse{
+		ContinueToken: workspaceList.Continue,
+		Workspaces:    projected,
+	}
+
 	response.JSONSuccess(w, 200,
 		response.NewJSONSuccess(
 			response.SuccessCodes.Ok,
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/forkspacer/api-server/pkg/services/forkspacer/forkspacertest"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var workspaceKind = schema.GroupKind{Group: batchv1.GroupVersion.Group, Kind: "Workspace"}
+
+// failCreate fails every create with err.
+func failCreate(err error) interceptor.Funcs {
+	return interceptor.Funcs{
+		Create: func(context.Context, client.WithWatch, client.Object, ...client.CreateOption) error {
+			return err
+		},
+	}
+}
+
+func TestWorkspaceHandlers(t *testing.T) {
+	const newWorkspace = `{"name":"staging","connection":{"type":"in-cluster"}}`
+
+	tests := []handlerTest{
+		{
+			name:       "create",
+			method:     "POST",
+			target:     "/workspace",
+			body:       newWorkspace,
+			wantStatus: 201,
+			wantCode:   "created",
+			check: func(t *testing.T, kubeClient client.Client, data any) {
+				if forkspacertest.Member(data, "name") != "staging" || forkspacertest.Member(data, "namespace") != "default" {
+					t.Errorf("data = %v, want the staging workspace of the default namespace", data)
+				}
+				if !forkspacertest.GetObject(t, kubeClient, &batchv1.Workspace{}, "default", "staging") {
+					t.Error("workspace was not created")
+				}
+			},
+		},
+		{
+			name:       "create dry run",
+			method:     "POST",
+			target:     "/workspace?dryRun=true",
+			body:       newWorkspace,
+			wantStatus: 201,
+			wantCode:   "created",
+			check: func(t *testing.T, kubeClient client.Client, data any) {
+				if forkspacertest.Member(data, "kind") != "Workspace" ||
+					forkspacertest.Member(data, "spec", "type") != "kubernetes" {
+					t.Errorf("data = %v, want the defaulted workspace", data)
+				}
+				if forkspacertest.GetObject(t, kubeClient, &batchv1.Workspace{}, "default", "staging") {
+					t.Error("dry run created the workspace")
+				}
+			},
+		},
+		{
+			name:       "invalid dry run",
+			method:     "POST",
+			target:     "/workspace?dryRun=maybe",
+			body:       newWorkspace,
+			wantStatus: 400,
+			wantCode:   "query_validation",
+		},
+		{
+			name:       "malformed body",
+			method:     "POST",
+			target:     "/workspace",
+			body:       `{"name":`,
+			wantStatus: 400,
+			wantCode:   "malformed_json_body",
+		},
+		{
+			name:       "missing connection",
+			method:     "POST",
+			target:     "/workspace",
+			body:       `{"name":"staging"}`,
+			wantStatus: 400,
+			wantCode:   "body_validation",
+		},
+		{
+			name:       "namespace outside the policy",
+			method:     "POST",
+			target:     "/workspace",
+			body:       `{"name":"staging","namespace":"kube-system","connection":{"type":"in-cluster"}}`,
+			wantStatus: 403,
+			wantCode:   "forbidden",
+		},
+		{
+			name:       "already exists",
+			method:     "POST",
+			target:     "/workspace",
+			body:       `{"name":"dev","connection":{"type":"in-cluster"}}`,
+			wantStatus: 409,
+			wantCode:   "conflict",
+		},
+		{
+			name:   "invalid object",
+			method: "POST",
+			target: "/workspace",
+			body:   newWorkspace,
+			funcs: failCreate(apierrors.NewInvalid(workspaceKind, "staging", field.ErrorList{
+				field.NotSupported(field.NewPath("spec", "type"), "cloud", []string{"kubernetes", "managed"}),
+			})),
+			wantStatus: 422,
+			wantCode:   "body_validation",
+			check: func(t *testing.T, _ client.Client, data any) {
+				if forkspacertest.Member(data, "spec.type") == nil {
+					t.Errorf("data = %v, want the cause keyed by spec.type", data)
+				}
+			},
+		},
+		{
+			name:   "denied by an admission webhook",
+			method: "POST",
+			target: "/workspace",
+			body:   newWorkspace,
+			funcs: failCreate(apierrors.NewForbidden(
+				batchv1.GroupVersion.WithResource("workspaces").GroupResource(), "staging",
+				errors.New(`admission webhook "vworkspace.kb.io" denied the request: schedule is invalid`),
+			)),
+			wantStatus: 422,
+			wantCode:   "body_validation",
+		},
+		{
+			name:       "API server unavailable",
+			method:     "POST",
+			target:     "/workspace",
+			body:       newWorkspace,
+			funcs:      failCreate(apierrors.NewServiceUnavailable("etcd is unavailable")),
+			wantStatus: 503,
+			wantCode:   "unavailable",
+		},
+		{
+			name:       "API server unreachable",
+			method:     "POST",
+			target:     "/workspace",
+			body:       newWorkspace,
+			funcs:      failCreate(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}),
+			wantStatus: 503,
+			wantCode:   "unavailable",
+		},
+		{
+			name:       "update",
+			method:     "PATCH",
+			target:     "/workspace",
+			body:       `{"name":"dev","hibernated":true}`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, kubeClient client.Client, _ any) {
+				workspace := &batchv1.Workspace{}
+				if forkspacertest.GetObject(t, kubeClient, workspace, "default", "dev"); !workspace.Spec.Hibernated {
+					t.Error("workspace was not hibernated")
+				}
+			},
+		},
+		{
+			name:       "update dry run",
+			method:     "PATCH",
+			target:     "/workspace?dryRun=true",
+			body:       `{"name":"dev","hibernated":true}`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, kubeClient client.Client, data any) {
+				if forkspacertest.Member(data, "kind") != "Workspace" || forkspacertest.Member(data, "spec", "hibernated") != true {
+					t.Errorf("data = %v, want the updated workspace", data)
+				}
+				workspace := &batchv1.Workspace{}
+				if forkspacertest.GetObject(t, kubeClient, workspace, "default", "dev"); workspace.Spec.Hibernated {
+					t.Error("dry run hibernated the workspace")
+				}
+			},
+		},
+		{
+			name:       "update missing",
+			method:     "PATCH",
+			target:     "/workspace",
+			body:       `{"name":"prod","hibernated":true}`,
+			wantStatus: 404,
+			wantCode:   "not_found",
+		},
+		{
+			name:   "update conflict",
+			method: "PATCH",
+			target: "/workspace",
+			body:   `{"name":"dev","hibernated":true}`,
+			funcs: interceptor.Funcs{
+				Update: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.UpdateOption) error {
+					return apierrors.NewConflict(
+						batchv1.GroupVersion.WithResource("workspaces").GroupResource(), obj.GetName(),
+						errors.New("the object has been modified"),
+					)
+				},
+			},
+			wantStatus: 409,
+			wantCode:   "conflict",
+		},
+		{
+			name:       "delete",
+			method:     "DELETE",
+			target:     "/workspace",
+			body:       `{"name":"dev"}`,
+			wantStatus: 204,
+			check: func(t *testing.T, kubeClient client.Client, _ any) {
+				if forkspacertest.GetObject(t, kubeClient, &batchv1.Workspace{}, "default", "dev") {
+					t.Error("workspace was not deleted")
+				}
+			},
+		},
+		{
+			name:       "delete dry run",
+			method:     "DELETE",
+			target:     "/workspace?dryRun=true",
+			body:       `{"name":"dev"}`,
+			wantStatus: 204,
+			check: func(t *testing.T, kubeClient client.Client, _ any) {
+				if !forkspacertest.GetObject(t, kubeClient, &batchv1.Workspace{}, "default", "dev") {
+					t.Error("dry run deleted the workspace")
+				}
+			},
+		},
+		{
+			name:       "delete missing",
+			method:     "DELETE",
+			target:     "/workspace",
+			body:       `{"name":"prod"}`,
+			wantStatus: 404,
+			wantCode:   "not_found",
+		},
+		{
+			name:       "delete kubeconfig secret",
+			method:     "DELETE",
+			target:     "/workspace/connection/kubeconfig",
+			body:       `{"name":"dev-kubeconfig"}`,
+			wantStatus: 204,
+			check: func(t *testing.T, kubeClient client.Client, _ any) {
+				if forkspacertest.GetObject(t, kubeClient, &corev1.Secret{}, "default", "dev-kubeconfig") {
+					t.Error("kubeconfig secret was not deleted")
+				}
+			},
+		},
+		{
+			name:       "delete a secret that is not a kubeconfig secret",
+			method:     "DELETE",
+			target:     "/workspace/connection/kubeconfig",
+			body:       `{"name":"registry-token"}`,
+			wantStatus: 404,
+			wantCode:   "not_found",
+			check: func(t *testing.T, kubeClient client.Client, _ any) {
+				if !forkspacertest.GetObject(t, kubeClient, &corev1.Secret{}, "default", "registry-token") {
+					t.Error("secret was deleted")
+				}
+			},
+		},
+		{
+			name:       "list counts modules in every namespace",
+			method:     "GET",
+			target:     "/workspace/list",
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, _ client.Client, data any) {
+				workspaces, _ := forkspacertest.Member(data, "workspaces").([]any)
+				if len(workspaces) != 1 || forkspacertest.Member(workspaces[0], "moduleCount") != 2.0 {
+					t.Errorf("workspaces = %v, want dev with 2 modules", workspaces)
+				}
+			},
+		},
+		{
+			name:       "list a namespace outside the policy",
+			method:     "GET",
+			target:     "/workspace/list?namespace=kube-system",
+			wantStatus: 403,
+			wantCode:   "forbidden",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, forkspacertest.Objects()...)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package v2
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/forkspacer/api-server/pkg/api/identity"
+	"github.com/forkspacer/api-server/pkg/api/middleware"
+	v1handlers "github.com/forkspacer/api-server/pkg/api/v1/handlers"
+	"github.com/forkspacer/api-server/pkg/api/v2/handlers"
+	"github.com/forkspacer/api-server/pkg/audit"
+	"github.com/forkspacer/api-server/pkg/config"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+const Version = "v2"
+
+//go:embed docs.html
+var docsHTML []byte
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// NewRouter serves the resource-oriented API, where every object is addressed by its path.
+// It shares its services and middlewares with v1.
+func NewRouter(
+	logger *zap.Logger,
+	configStore *config.Store,
+	corsMiddleware *middleware.CORS,
+	auditor *audit.Auditor,
+	rateLimiter *middleware.RateLimiter,
+	inFlightLimiter *middleware.InFlightLimiter,
+	idempotency *middleware.Idempotency,
+	forkspacerWorkspaceService *forkspacer.ForkspacerWorkspaceService,
+	forkspacerModuleService *forkspacer.ForkspacerModuleService,
+) http.Handler {
+	// Settings read here only change on restart; reloadable ones are applied by their middlewares
+	apiConfig := configStore.Load()
+
+	workspaceHandler := handlers.NewWorkspaceHandler(logger, forkspacerWorkspaceService, forkspacerModuleService)
+	moduleHandler := handlers.NewModuleHandler(logger, forkspacerModuleService)
+	applyHandler := handlers.NewApplyHandler(logger, auditor, forkspacerWorkspaceService, forkspacerModuleService)
+	kubeconfigHandler := handlers.NewKubeconfigHandler(logger, forkspacerWorkspaceService, apiConfig.StrictKubeconfig)
+
+	apiRouter := chi.NewRouter()
+	apiRouter.Use(corsMiddleware.Middleware)
+	apiRouter.Use(identity.HeaderMiddleware(apiConfig.IdentityUserHeader, apiConfig.IdentityGroupsHeader))
+	apiRouter.Use(middleware.AccessLog(logger))
+
+	apiRouter.Get("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, err := w.Write(docsHTML)
+		if err != nil {
+			logger.Error("failed to write docs HTML response", zap.Error(err))
+		}
+	})
+
+	apiRouter.Get("/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-yaml; charset=utf-8")
+		_, err := w.Write(openAPISpec)
+		if err != nil {
+			logger.Error("failed to write OpenAPI spec response", zap.Error(err))
+		}
+	})
+
+	apiRouter.Group(func(r chi.Router) {
+		r.Use(rateLimiter.Middleware)
+		r.Use(middleware.BodyLimit(apiConfig.MaxBodyBytes))
+
+		// Routes hitting the Kubernetes API
+		r.Group(func(r chi.Router) {
+			r.Use(inFlightLimiter.Middleware)
+			r.Use(middleware.Timeout(apiConfig.RequestTimeout))
+
+			r.Get("/workspaces", workspaceHandler.ListAllHandle)
+			r.Get("/modules", moduleHandler.ListAllHandle)
+			r.Post("/apply", applyHandler.ApplyHandle)
+
+			r.Route("/namespaces/{namespace}", func(r chi.Router) {
+				r.Get("/modules", moduleHandler.ListNamespaceHandle)
+
+				r.Route("/workspaces", func(r chi.Router) {
+					r.Group(func(r chi.Router) {
+						r.Use(auditor.PathMiddleware(audit.WorkspaceObject, "workspace"))
+						r.Get("/", workspaceHandler.ListHandle)
+						r.With(idempotency.Middleware).Post("/", workspaceHandler.CreateHandle)
+						r.Get("/{workspace}", workspaceHandler.GetHandle)
+						r.Put("/{workspace}", workspaceHandler.ReplaceHandle)
+						r.Patch("/{workspace}", workspaceHandler.PatchHandle)
+						r.Delete("/{workspace}", workspaceHandler.DeleteHandle)
+					})
+
+					r.Route("/{workspace}/modules", func(r chi.Router) {
+						r.Use(auditor.PathMiddleware(audit.ModuleObject, "module"))
+						r.Get("/", moduleHandler.ListHandle)
+						r.With(idempotency.Middleware).Post("/", moduleHandler.CreateHandle)
+						r.Get("/{module}", moduleHandler.GetHandle)
+						r.Put("/{module}", moduleHandler.ReplaceHandle)
+						r.Patch("/{module}", moduleHandler.PatchHandle)
+						r.Delete("/{module}", moduleHandler.DeleteHandle)
+					})
+				})
+
+				r.Route("/kubeconfigs", func(r chi.Router) {
+					r.Use(auditor.PathMiddleware(audit.SecretObject, "kubeconfig"))
+					r.Get("/", kubeconfigHandler.ListHandle)
+					r.With(middleware.BodyLimit(v1handlers.KubeconfigUploadMaxBytes), idempotency.Middleware).
+						Post("/", kubeconfigHandler.CreateHandle)
+					r.Delete("/{kubeconfig}", kubeconfigHandler.DeleteHandle)
+				})
+			})
+		})
+	})
+
+	return apiRouter
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+	v1handlers "github.com/forkspacer/api-server/pkg/api/v1/handlers"
+	"github.com/forkspacer/api-server/pkg/api/validation"
+	"github.com/forkspacer/api-server/pkg/audit"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	"github.com/forkspacer/api-server/pkg/utils"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applyResultError is the result of a manifest that could not be applied.
+const applyResultError = "error"
+
+type ApplyHandler struct {
+	logger                     *zap.Logger
+	auditor                    *audit.Auditor
+	forkspacerWorkspaceService *forkspacer.ForkspacerWorkspaceService
+	forkspacerModuleService    *forkspacer.ForkspacerModuleService
+}
+
+func NewApplyHandler(
+	logger *zap.Logger,
+	auditor *audit.Auditor,
+	forkspacerWorkspaceService *forkspacer.ForkspacerWorkspaceService,
+	forkspacerModuleService *forkspacer.ForkspacerModuleService,
+) *ApplyHandler {
+	return &ApplyHandler{logger, auditor, forkspacerWorkspaceService, forkspacerModuleService}
+}
+
+// ApplyObjectResult is what applying a manifest did: created, configured, unchanged or error.
+type ApplyObjectResult struct {
+	APIVersion string                      `json:"apiVersion,omitempty"`
+	Kind       string                      `json:"kind,omitempty"`
+	Name       string                      `json:"name,omitempty"`
+	Namespace  string                      `json:"namespace,omitempty"`
+	Result     string                      `json:"result"`
+	Error      *response.JSONErrorResponse `json:"error,omitempty"`
+}
+
+type ApplyResponse struct {
+	// Items holds a result per manifest, in the order of the request
+	Items []ApplyObjectResult `json:"items"`
+}
+
+// ApplyHandle applies YAML or JSON manifests of Workspaces and Modules with server-side apply.
+// Manifests are validated like create requests, and applied one by one: a manifest failing
+// is reported in its result and does not stop the others.
+func (h ApplyHandler) ApplyHandle(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := v1handlers.ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
+	force := false
+	if r.URL.Query().Has("force") {
+		force, err = utils.ParseString[bool](r.URL.Query().Get("force"))
+		if err != nil {
+			response.JSONQueryValidationError(w, map[string]string{"force": "force must be true or false"})
+			return
+		}
+	}
+
+	manifests, err := readManifests(w, r)
+	if err != nil {
+		return
+	}
+
+	opts := v1handlers.DryRunOptions[client.ApplyOption](dryRun)
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+
+	items := make([]ApplyObjectResult, len(manifests))
+	for i, manifest := range manifests {
+		items[i] = h.apply(r, manifest, opts)
+	}
+
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, ApplyResponse{Items: items}))
+}
+
+// apply applies a manifest and records it in the audit log, as if it had been sent on its own.
+func (h ApplyHandler) apply(
+	r *http.Request, manifest *unstructured.Unstructured, opts []client.ApplyOption,
+) ApplyObjectResult {
+	body := manifest.DeepCopy().Object
+	item, status := h.applyManifest(r, manifest, opts)
+
+	h.auditor.RecordObject(r, audit.ObjectReference{
+		APIVersion: item.APIVersion,
+		Kind:       item.Kind,
+		Name:       item.Name,
+		Namespace:  item.Namespace,
+	}, body, status)
+
+	return item
+}
+
+// applyManifest returns the result of applying a manifest, and the status code of a request making the same change.
+func (h ApplyHandler) applyManifest(
+	r *http.Request, manifest *unstructured.Unstructured, opts []client.ApplyOption,
+) (ApplyObjectResult, int) {
+	item := ApplyObjectResult{
+		APIVersion: manifest.GetAPIVersion(),
+		Kind:       manifest.GetKind(),
+		Name:       manifest.GetName(),
+		Namespace:  manifest.GetNamespace(),
+		Result:     applyResultError,
+	}
+
+	if errs, err := validateManifest(r, manifest); err != nil {
+		item.Error = response.NewJSONError(response.ErrCodes.BadRequest, err.Error())
+		return item, 400
+	} else if errs != nil {
+		item.Error = response.NewJSONError(response.ErrCodes.BodyValidation, errs)
+		return item, 400
+	}
+
+	var (
+		result forkspacer.ApplyResult
+		err    error
+	)
+	if manifest.GetKind() == "Workspace" {
+		_, result, err = h.forkspacerWorkspaceService.Apply(r.Context(), manifest, opts...)
+	} else {
+		_, result, err = h.forkspacerModuleService.Apply(r.Context(), manifest, opts...)
+	}
+	// Manifests without a namespace were applied to the default one
+	item.Namespace = manifest.GetNamespace()
+	if err != nil {
+		v1handlers.LogServiceError(r, h.logger, "failed to apply "+strings.ToLower(item.Kind), err)
+		var status int
+		status, item.Error = v1handlers.ServiceErrorResponse(err)
+		return item, status
+	}
+
+	item.Result = string(result)
+	if result == forkspacer.ApplyCreated {
+		return item, 201
+	}
+	return item, 200
+}
+
+// readManifests reads the manifests of an apply request: YAML documents, or a JSON object.
+// Lists of kind List are expanded into their items.
+func readManifests(w http.ResponseWriter, r *http.Request) ([]*unstructured.Unstructured, error) {
+	contentType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+	if contentType != response.MediaTypeJSON && !response.IsYAMLMediaType(contentType) {
+		response.JSONUnsopportedMediaType(w, response.MediaTypeYAML+" or "+response.MediaTypeJSON)
+		return nil, fmt.Errorf("unsupported media type")
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			response.JSONBodyTooLarge(w, maxBytesErr.Limit)
+			return nil, fmt.Errorf("request body too large: %w", err)
+		}
+
+		response.JSONMalformedJSONBody(w)
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	var manifests []*unstructured.Unstructured
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for document := 1; ; document++ {
+		documentData, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err == nil {
+			documentData, err = utilyaml.ToJSON(documentData)
+		}
+		if err != nil {
+			response.JSONBadRequest(w, fmt.Sprintf("document %d is not valid YAML or JSON: %v", document, err))
+			return nil, fmt.Errorf("failed to read manifest %d: %w", document, err)
+		}
+		if string(documentData) == "null" {
+			continue
+		}
+
+		manifest := &unstructured.Unstructured{}
+		if err := json.Unmarshal(documentData, &manifest.Object); err != nil {
+			response.JSONBadRequest(w, fmt.Sprintf("document %d is not an object", document))
+			return nil, fmt.Errorf("failed to decode manifest %d: %w", document, err)
+		}
+
+		if manifest.IsList() {
+			list, err := manifest.ToList()
+			if err != nil {
+				response.JSONBadRequest(w, fmt.Sprintf("document %d is not a valid list: %v", document, err))
+				return nil, fmt.Errorf("failed to decode list %d: %w", document, err)
+			}
+			for i := range list.Items {
+				manifests = append(manifests, &list.Items[i])
+			}
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	if len(manifests) == 0 {
+		response.JSONBodyValidationError(w, map[string]string{"manifests": "at least one manifest is required"})
+		return nil, fmt.Errorf("no manifests to apply")
+	}
+
+	return manifests, nil
+}
+
+// validateManifest validates a manifest like the create request of its kind. It returns validation errors
+// keyed like those of a request body, or an error for a manifest that is not a Workspace or Module.
+func validateManifest(r *http.Request, manifest *unstructured.Unstructured) (map[string]string, error) {
+	gvk := manifest.GroupVersionKind()
+	if gvk.GroupVersion() != batchv1.GroupVersion || (gvk.Kind != "Workspace" && gvk.Kind != "Module") {
+		return nil, fmt.Errorf(
+			"%s %s cannot be applied, only Workspace and Module of %s", manifest.GetAPIVersion(), gvk.Kind,
+			batchv1.GroupVersion,
+		)
+	}
+
+	var requestData any
+	if gvk.Kind == "Workspace" {
+		workspace := &batchv1.Workspace{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructuredWithValidation(
+			manifest.Object, workspace, true,
+		); err != nil {
+			return map[string]string{"object": err.Error()}, nil
+		}
+		requestData = workspaceCreateRequest(workspace)
+	} else {
+		module := &batchv1.Module{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructuredWithValidation(
+			manifest.Object, module, true,
+		); err != nil {
+			return map[string]string{"object": err.Error()}, nil
+		}
+		moduleRequestData, err := moduleCreateRequest(module)
+		if err != nil {
+			return map[string]string{"object": err.Error()}, nil
+		}
+		if errs := moduleRequestData.SourceErrors(); errs != nil {
+			return errs, nil
+		}
+		requestData = moduleRequestData
+	}
+
+	if err := validation.Validate.StructCtx(r.Context(), requestData); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return nil, err
+		}
+		return validationErrs.Translate(validation.Translator(r.Context())), nil
+	}
+
+	return nil, nil
+}
+
+// workspaceCreateRequest returns the create request of a workspace manifest.
+func workspaceCreateRequest(workspace *batchv1.Workspace) *v1handlers.CreateWorkspaceRequest {
+	requestData := &v1handlers.CreateWorkspaceRequest{
+		Name:       workspace.Name,
+		Hibernated: workspace.Spec.Hibernated,
+		Connection: &v1handlers.WorkspaceConnection{Type: string(workspace.Spec.Connection.Type)},
+		AutoHibernation: &v1handlers.WorkspaceAutoHibernation{
+			Enabled:      workspace.Spec.AutoHibernation.Enabled,
+			Schedule:     workspace.Spec.AutoHibernation.Schedule,
+			WakeSchedule: workspace.Spec.AutoHibernation.WakeSchedule,
+		},
+	}
+
+	if workspace.Namespace != "" {
+		requestData.Namespace = utils.ToPtr(workspace.Namespace)
+	}
+	if workspace.Spec.Type != "" {
+		requestData.Type = utils.ToPtr(string(workspace.Spec.Type))
+	}
+	if from := workspace.Spec.From; from != nil {
+		requestData.From = &v1handlers.WorkspaceResourceReference{Name: from.Name, Namespace: from.Namespace}
+	}
+	if secretReference := workspace.Spec.Connection.SecretReference; secretReference != nil {
+		requestData.Connection.Secret = &v1handlers.WorkspaceResourceReference{
+			Name:      secretReference.Name,
+			Namespace: secretReference.Namespace,
+		}
+		if secretReference.Key != "" {
+			requestData.Connection.Key = utils.ToPtr(secretReference.Key)
+		}
+	}
+	if managedCluster := workspace.Spec.ManagedCluster; managedCluster != nil {
+		requestData.ManagedCluster = &v1handlers.ManagedCluster{}
+		if managedCluster.Backend != "" {
+			requestData.ManagedCluster.Backend = utils.ToPtr(string(managedCluster.Backend))
+		}
+		if managedCluster.Distro != "" {
+			requestData.ManagedCluster.Distro = utils.ToPtr(managedCluster.Distro)
+		}
+	}
+
+	return requestData
+}
+
+// moduleCreateRequest returns the create request of a module manifest.
+// The helm, custom and config fields of the request are shaped like those of the module.
+func moduleCreateRequest(module *batchv1.Module) (*v1handlers.CreateModuleRequest, error) {
+	data, err := json.Marshal(map[string]any{
+		"name":         module.Name,
+		"workspace":    module.Spec.Workspace,
+		"helm":         module.Spec.Helm,
+		"custom":       module.Spec.Custom,
+		"config":       module.Spec.Config,
+		"configSchema": module.Config,
+		"hibernated":   module.Spec.Hibernated,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	requestData := &v1handlers.CreateModuleRequest{}
+	if err := json.Unmarshal(data, requestData); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("%s must be of type %s", typeErr.Field, typeErr.Type)
+		}
+		return nil, err
+	}
+	if module.Namespace != "" {
+		requestData.Namespace = utils.ToPtr(module.Namespace)
+	}
+
+	return requestData, nil
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadManifestsMediaTypes(t *testing.T) {
+	const manifest = "apiVersion: batch.forkspacer.com/v1\nkind: Workspace\nmetadata:\n  name: dev\n"
+
+	tests := []struct {
+		contentType string
+		wantStatus  int
+	}{
+		{contentType: "application/yaml", wantStatus: 200},
+		{contentType: "application/x-yaml", wantStatus: 200},
+		{contentType: "text/yaml; charset=utf-8", wantStatus: 200},
+		{contentType: "application/json", wantStatus: 200},
+		{contentType: "text/plain", wantStatus: 415},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/apply", strings.NewReader(manifest))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			manifests, err := readManifests(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == 200 && (err != nil || len(manifests) != 1) {
+				t.Errorf("manifests = %v, %v, want the workspace", manifests, err)
+			}
+		})
+	}
+}
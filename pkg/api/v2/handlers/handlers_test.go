@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer/forkspacertest"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// handlerTest is a request to the v2 routes and the response expected from it.
+type handlerTest struct {
+	name   string
+	method string
+	target string
+	// header holds request headers. JSON bodies need no Content-Type.
+	header map[string]string
+	body   string
+	// funcs intercept the calls the services make to the backend, e.g. to fail them
+	funcs      interceptor.Funcs
+	wantStatus int
+	// wantCode is the code of the success or error in the response, empty when there is no body
+	wantCode string
+	// check inspects the response and the backend after the request
+	check func(t *testing.T, kubeClient client.Client, header http.Header, data any)
+}
+
+func (tt handlerTest) run(t *testing.T, objects ...client.Object) {
+	t.Helper()
+
+	kubeClient := forkspacertest.NewClient(t, tt.funcs, objects...)
+	workspaceService := forkspacer.NewForkspacerWorkspaceService(kubeClient, forkspacertest.Namespaces)
+	moduleService := forkspacer.NewForkspacerModuleService(kubeClient, forkspacertest.Namespaces)
+
+	router := chi.NewRouter()
+	router.Route("/namespaces/{namespace}/workspaces", func(r chi.Router) {
+		workspaceHandler := NewWorkspaceHandler(zap.NewNop(), workspaceService, moduleService)
+		r.Get("/", workspaceHandler.ListHandle)
+		r.Post("/", workspaceHandler.CreateHandle)
+		r.Get("/{workspace}", workspaceHandler.GetHandle)
+		r.Put("/{workspace}", workspaceHandler.ReplaceHandle)
+		r.Patch("/{workspace}", workspaceHandler.PatchHandle)
+		r.Delete("/{workspace}", workspaceHandler.DeleteHandle)
+
+		r.Route("/{workspace}/modules", func(r chi.Router) {
+			moduleHandler := NewModuleHandler(zap.NewNop(), moduleService)
+			r.Get("/", moduleHandler.ListHandle)
+			r.Post("/", moduleHandler.CreateHandle)
+			r.Get("/{module}", moduleHandler.GetHandle)
+			r.Put("/{module}", moduleHandler.ReplaceHandle)
+			r.Patch("/{module}", moduleHandler.PatchHandle)
+			r.Delete("/{module}", moduleHandler.DeleteHandle)
+		})
+	})
+
+	r := forkspacertest.NewRequest(tt.method, tt.target, tt.body)
+	for name, value := range tt.header {
+		r.Header.Set(name, value)
+	}
+	header, data := forkspacertest.Serve(t, router, r, tt.wantStatus, tt.wantCode)
+
+	if tt.check != nil {
+		tt.check(t, kubeClient, header, data)
+	}
+}
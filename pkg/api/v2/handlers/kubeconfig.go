@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+	v1handlers "github.com/forkspacer/api-server/pkg/api/v1/handlers"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	"go.uber.org/zap"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// KubeconfigHandler serves the secrets holding the kubeconfigs workspaces connect with.
+type KubeconfigHandler struct {
+	logger                     *zap.Logger
+	forkspacerWorkspaceService *forkspacer.ForkspacerWorkspaceService
+	strictKubeconfig           bool
+}
+
+func NewKubeconfigHandler(
+	logger *zap.Logger,
+	forkspacerWorkspaceService *forkspacer.ForkspacerWorkspaceService,
+	strictKubeconfig bool,
+) *KubeconfigHandler {
+	return &KubeconfigHandler{logger, forkspacerWorkspaceService, strictKubeconfig}
+}
+
+type ListKubeconfigsResponse struct {
+	ContinueToken string                                `json:"continueToken"`
+	Items         []v1handlers.KubeconfigSecretResponse `json:"items"`
+}
+
+func (h KubeconfigHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	query, err := readListQuery(w, r)
+	if err != nil {
+		return
+	}
+
+	secrets, err := h.forkspacerWorkspaceService.ListKubeconfigSecrets(
+		r.Context(), &objectPath.Namespace, *query.Limit, query.ContinueToken,
+	)
+	if err != nil {
+		serviceError(w, r, h.logger, "failed to list kubeconfig secrets", err)
+		return
+	}
+
+	responseData := ListKubeconfigsResponse{
+		ContinueToken: secrets.Continue,
+		Items:         make([]v1handlers.KubeconfigSecretResponse, len(secrets.Items)),
+	}
+	for i, secret := range secrets.Items {
+		responseData.Items[i] = v1handlers.KubeconfigSecretResponse{
+			Name:      secret.Name,
+			Namespace: secret.Namespace,
+		}
+	}
+
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, responseData))
+}
+
+// CreateHandle stores an uploaded kubeconfig in the namespace of the path.
+// The form is the v1 upload form, whose namespace may be omitted.
+func (h KubeconfigHandler) CreateHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	dryRun, err := v1handlers.ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
+	requestData, err := v1handlers.ReadKubeconfigSecretRequest(w, r, h.logger, h.strictKubeconfig)
+	if err != nil {
+		return
+	}
+	if err := matchPath(w, "namespace", &requestData.Namespace, objectPath.Namespace); err != nil {
+		return
+	}
+
+	secret, err := h.forkspacerWorkspaceService.CreateKubeconfigSecret(
+		r.Context(), requestData.Name, requestData.Namespace, requestData.Kubeconfig,
+		v1handlers.DryRunOptions[client.CreateOption](dryRun)...,
+	)
+	if err != nil {
+		serviceError(w, r, h.logger, "failed to create kubeconfig secret", err)
+		return
+	}
+
+	if !dryRun {
+		w.Header().Set("Location", createdLocation(r, secret.Name))
+	}
+	response.JSONSuccess(w, 201,
+		response.NewJSONSuccess(
+			response.SuccessCodes.Created,
+			v1handlers.KubeconfigSecretResponse{
+				Name:      secret.Name,
+				Namespace: secret.Namespace,
+			},
+		),
+	)
+}
+
+func (h KubeconfigHandler) DeleteHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	dryRun, err := v1handlers.ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
+	if err := h.forkspacerWorkspaceService.DeleteKubeconfigSecret(
+		r.Context(), objectPath.Kubeconfig, &objectPath.Namespace, v1handlers.DryRunOptions[client.DeleteOption](dryRun)...,
+	); err != nil {
+		serviceError(w, r, h.logger, "failed to delete kubeconfig secret", err)
+		return
+	}
+
+	response.JSONDeleted(w)
+}
@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/api/etag"
+	"github.com/forkspacer/api-server/pkg/api/response"
+	v1handlers "github.com/forkspacer/api-server/pkg/api/v1/handlers"
+	"github.com/forkspacer/api-server/pkg/api/validation"
+	"github.com/forkspacer/api-server/pkg/logging"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ModuleHandler serves the modules of a workspace, which live in the workspace's namespace.
+type ModuleHandler struct {
+	logger                  *zap.Logger
+	forkspacerModuleService *forkspacer.ForkspacerModuleService
+}
+
+func NewModuleHandler(
+	logger *zap.Logger,
+	forkspacerModuleService *forkspacer.ForkspacerModuleService,
+) *ModuleHandler {
+	return &ModuleHandler{logger, forkspacerModuleService}
+}
+
+type Module struct {
+	Name            string                        `json:"name"`
+	Namespace       string                        `json:"namespace"`
+	Workspace       v1handlers.WorkspaceReference `json:"workspace"`
+	Type            string                        `json:"type"`
+	Hibernated      bool                          `json:"hibernated"`
+	Config          json.RawMessage               `json:"config,omitempty"`
+	Phase           string                        `json:"phase"`
+	Message         string                        `json:"message"`
+	CreatedAt       time.Time                     `json:"createdAt,omitzero"`
+	Age             string                        `json:"age,omitempty"`
+	Labels          map[string]string             `json:"labels,omitempty"`
+	StatusUpdatedAt time.Time                     `json:"statusUpdatedAt,omitzero"`
+}
+
+func newModule(module *batchv1.Module) Module {
+	responseData := Module{
+		Name:      module.Name,
+		Namespace: module.Namespace,
+		Workspace: v1handlers.WorkspaceReference{
+			Name:      module.Spec.Workspace.Name,
+			Namespace: module.Spec.Workspace.Namespace,
+		},
+		Type:            module.Status.Source,
+		Hibernated:      module.Spec.Hibernated,
+		Phase:           string(module.Status.Phase),
+		CreatedAt:       module.CreationTimestamp.Time,
+		Age:             v1handlers.ObjectAge(module),
+		Labels:          module.Labels,
+		StatusUpdatedAt: v1handlers.StatusUpdatedAt(module),
+	}
+
+	if module.Spec.Config != nil {
+		responseData.Config = module.Spec.Config.Raw
+	}
+
+	if module.Status.Message != nil {
+		responseData.Message = *module.Status.Message
+	}
+
+	return responseData
+}
+
+type ListModulesResponse struct {
+	ContinueToken string `json:"continueToken"`
+	// Items holds Modules, restricted to the requested fields if any
+	Items any `json:"items"`
+}
+
+// ListAllHandle lists modules across every namespace the API may touch.
+func (h ModuleHandler) ListAllHandle(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, nil)
+}
+
+// ListNamespaceHandle lists the modules of every workspace in the namespace of the path.
+func (h ModuleHandler) ListNamespaceHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	h.list(w, r, &objectPath.Namespace)
+}
+
+func (h ModuleHandler) list(w http.ResponseWriter, r *http.Request, namespace *string) {
+	query, err := readListQuery(w, r)
+	if err != nil {
+		return
+	}
+
+	fields, err := v1handlers.ReadListFields[Module](w, r)
+	if err != nil {
+		return
+	}
+
+	moduleList, err := h.forkspacerModuleService.List(
+		r.Context(), namespace, *query.Limit, query.ContinueToken, query.Sort,
+	)
+	if err != nil {
+		serviceError(w, r, h.logger, "failed to list modules", err)
+		return
+	}
+
+	items := make([]Module, len(moduleList.Items))
+	for i := range moduleList.Items {
+		items[i] = newModule(&moduleList.Items[i])
+	}
+
+	h.respondList(w, r, moduleList.Continue, items, fields)
+}
+
+// ListHandle lists the modules of the workspace in the path. The list is not paginated.
+func (h ModuleHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	sort, err := v1handlers.ReadListSort(w, r)
+	if err != nil {
+		return
+	}
+
+	fields, err := v1handlers.ReadListFields[Module](w, r)
+	if err != nil {
+		return
+	}
+
+	moduleList, err := h.forkspacerModuleService.ListByWorkspace(r.Context(), forkspacer.ResourceReference{
+		Name:      objectPath.Workspace,
+		Namespace: objectPath.Namespace,
+	}, sort)
+	if err != nil {
+		serviceError(w, r, h.logger, "failed to list workspace modules", err)
+		return
+	}
+
+	items := []Module{}
+	for i := range moduleList.Items {
+		if moduleList.Items[i].Namespace == objectPath.Namespace {
+			items = append(items, newModule(&moduleList.Items[i]))
+		}
+	}
+
+	h.respondList(w, r, "", items, fields)
+}
+
+func (h ModuleHandler) respondList(
+	w http.ResponseWriter, r *http.Request, continueToken string, items []Module, fields []string,
+) {
+	projected, err := v1handlers.ProjectFields(items, fields)
+	if err != nil {
+		logging.FromContext(r.Context(), h.logger).Error("failed to project module fields", zap.Error(err))
+		response.JSONInternal(w)
+		return
+	}
+
+	responseData := ListModulesResponse{ContinueToken: continueToken, Items: projected}
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, responseData))
+}
+
+// CreateHandle creates a module of the workspace in the path. The body is the v1 create
+// request, whose namespace and workspace may be omitted.
+func (h ModuleHandler) CreateHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	dryRun, err := v1handlers.ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
+	workspace := v1handlers.WorkspaceReference{Name: objectPath.Workspace, Namespace: objectPath.Namespace}
+	var requestData = &v1handlers.CreateModuleRequest{Workspace: workspace}
+	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
+		return
+	}
+	if err := matchPath(w, "namespace", &requestData.Namespace, objectPath.Namespace); err != nil {
+		return
+	}
+	if requestData.Workspace != workspace {
+		response.JSONBodyValidationError(w, map[string]string{
+			"workspace": fmt.Sprintf("workspace must match the path (%s/%s) or be omitted",
+				workspace.Namespace, workspace.Name),
+		})
+		return
+	}
+
+	if errs := requestData.SourceErrors(); errs != nil {
+		response.JSONBodyValidationError(w, errs)
+		return
+	}
+
+	module, err := h.forkspacerModuleService.Create(
+		r.Context(), requestData.ModuleCreateIn(), v1handlers.DryRunOptions[client.CreateOption](dryRun)...,
+	)
+	if err != nil {
+		serviceError(w, r, h.logger, "failed to create module", err)
+		return
+	}
+
+	if !dryRun {
+		w.Header().Set("Location", createdLocation(r, module.Name))
+		etag.Set(w, module.ResourceVersion)
+	}
+	response.JSONSuccess(w, 201, response.NewJSONSuccess(response.SuccessCodes.Created, newModule(module)))
+}
+
+func (h ModuleHandler) GetHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	module, err := h.get(r, objectPath)
+	if err != nil {
+		serviceError(w, r, h.logger, "failed to get module", err)
+		return
+	}
+
+	if etag.NoneMatch(r, module.ResourceVersion) {
+		etag.NotModified(w, module.ResourceVersion)
+		return
+	}
+
+	etag.Set(w, module.ResourceVersion)
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, newModule(module)))
+}
+
+// get returns the module in the path, which is reported missing unless it belongs to the workspace in the path.
+func (h ModuleHandler) get(r *http.Request, objectPath *ObjectPath) (*batchv1.Module, error) {
+	module, err := h.forkspacerModuleService.Get(r.Context(), objectPath.Module, &objectPath.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if module.Spec.Workspace.Name != objectPath.Workspace || module.Spec.Workspace.Namespace != objectPath.Namespace {
+		return nil, apierrors.NewNotFound(batchv1.GroupVersion.WithResource("modules").GroupResource(), module.Name)
+	}
+
+	return module, nil
+}
+
+// ReplaceModuleRequest holds every module setting that can change after creation.
+type ReplaceModuleRequest struct {
+	Hibernated bool `json:"hibernated"`
+}
+
+func (h ModuleHandler) ReplaceHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	resourceVersion, err := readIfMatch(w, r)
+	if err != nil {
+		return
+	}
+
+	dryRun, err := v1handlers.ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
+	var requestData = &ReplaceModuleRequest{}
+	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
+		return
+	}
+
+	h.update(w, r, objectPath, &requestData.Hibernated, resourceVersion, dryRun)
+}
+
+// PatchModuleRequest changes only the settings it sets.
+type PatchModuleRequest struct {
+	Hibernated *bool `json:"hibernated,omitempty"`
+}
+
+func (h ModuleHandler) PatchHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	resourceVersion, err := readIfMatch(w, r)
+	if err != nil {
+		return
+	}
+
+	dryRun, err := v1handlers.ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
+	patchIn, err := readPatch(w, r, modulePatchPaths)
+	if err != nil {
+		return
+	}
+	if patchIn != nil {
+		patchIn.ResourceVersion = resourceVersion
+		h.patch(w, r, objectPath, *patchIn, dryRun)
+		return
+	}
+
+	var requestData = &PatchModuleRequest{}
+	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
+		return
+	}
+
+	h.update(w, r, objectPath, requestData.Hibernated, resourceVersion, dryRun)
+}
+
+// PatchedModuleSpec holds the module settings a merge patch or JSON patch may change,
+// validated like those of a create request.
+type PatchedModuleSpec struct {
+	Hibernated bool                         `json:"hibernated"`
+	Helm       *v1handlers.ModuleSpecHelm   `json:"helm,omitempty"`
+	Custom     *v1handlers.ModuleSpecCustom `json:"custom,omitempty"`
+	Config     map[string]any               `json:"config,omitempty"`
+}
+
+// patch applies a merge patch or JSON patch to a module of the workspace in the path,
+// after validating the patched settings.
+func (h ModuleHandler) patch(
+	w http.ResponseWriter, r *http.Request, objectPath *ObjectPath, patchIn forkspacer.PatchIn, dryRun bool,
+) {
+	module, err := h.forkspacerModuleService.Patch(
+		r.Context(), objectPath.Module, &objectPath.Namespace, patchIn,
+		func(module *batchv1.Module) error {
+			if module.Spec.Workspace.Name != objectPath.Workspace || module.Spec.Workspace.Namespace != objectPath.Namespace {
+				return apierrors.NewNotFound(batchv1.GroupVersion.WithResource("modules").GroupResource(), module.Name)
+			}
+
+			spec := &PatchedModuleSpec{}
+			if err := validatePatchedSpec(r, module.Spec, spec); err != nil {
+				return err
+			}
+			source := v1handlers.CreateModuleRequest{Helm: spec.Helm, Custom: spec.Custom}
+			if errs := source.SourceErrors(); errs != nil {
+				return patchErrors(errs)
+			}
+
+			return nil
+		},
+		v1handlers.DryRunOptions[client.PatchOption](dryRun)...,
+	)
+	if err != nil {
+		patchError(w, r, h.logger, "failed to patch module", err)
+		return
+	}
+
+	if !dryRun {
+		etag.Set(w, module.ResourceVersion)
+	}
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, newModule(module)))
+}
+
+func (h ModuleHandler) update(
+	w http.ResponseWriter, r *http.Request, objectPath *ObjectPath, hibernated *bool, resourceVersion *string, dryRun bool,
+) {
+	if _, err := h.get(r, objectPath); err != nil {
+		serviceError(w, r, h.logger, "failed to update module", err)
+		return
+	}
+
+	module, err := h.forkspacerModuleService.Update(r.Context(), forkspacer.ModuleUpdateIn{
+		Name:            objectPath.Module,
+		Namespace:       &objectPath.Namespace,
+		Hibernated:      hibernated,
+		ResourceVersion: resourceVersion,
+	}, v1handlers.DryRunOptions[client.UpdateOption](dryRun)...)
+	if err != nil {
+		serviceError(w, r, h.logger, "failed to update module", err)
+		return
+	}
+
+	if !dryRun {
+		etag.Set(w, module.ResourceVersion)
+	}
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, newModule(module)))
+}
+
+func (h ModuleHandler) DeleteHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	resourceVersion, err := readIfMatch(w, r)
+	if err != nil {
+		return
+	}
+
+	dryRun, err := v1handlers.ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
+	if _, err := h.get(r, objectPath); err != nil {
+		serviceError(w, r, h.logger, "failed to delete module", err)
+		return
+	}
+
+	if err := h.forkspacerModuleService.Delete(
+		r.Context(), objectPath.Module, &objectPath.Namespace, deleteOptions(resourceVersion, dryRun)...,
+	); err != nil {
+		serviceError(w, r, h.logger, "failed to delete module", err)
+		return
+	}
+
+	response.JSONDeleted(w)
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/forkspacer/api-server/pkg/services/forkspacer/forkspacertest"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestModuleHandlers(t *testing.T) {
+	const (
+		modulesPath = "/namespaces/default/workspaces/dev/modules"
+		modulePath  = modulesPath + "/redis"
+	)
+
+	wantModuleHibernated := func(t *testing.T, kubeClient client.Client, want bool) {
+		t.Helper()
+
+		module := &batchv1.Module{}
+		if forkspacertest.GetObject(t, kubeClient, module, "default", "redis"); module.Spec.Hibernated != want {
+			t.Errorf("hibernated = %v, want %v", module.Spec.Hibernated, want)
+		}
+	}
+
+	tests := []handlerTest{
+		{
+			name:       "get",
+			method:     "GET",
+			target:     modulePath,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, _ client.Client, header http.Header, data any) {
+				if header.Get("ETag") != `"1"` {
+					t.Errorf("ETag = %q, want \"1\"", header.Get("ETag"))
+				}
+				if forkspacertest.Member(data, "name") != "redis" || forkspacertest.Member(data, "workspace", "name") != "dev" {
+					t.Errorf("data = %v, want the redis module", data)
+				}
+			},
+		},
+		{
+			name:       "get unmodified",
+			method:     "GET",
+			target:     modulePath,
+			header:     map[string]string{"If-None-Match": `W/"1"`},
+			wantStatus: 304,
+		},
+		{
+			name:       "get through another workspace",
+			method:     "GET",
+			target:     "/namespaces/default/workspaces/prod/modules/redis",
+			wantStatus: 404,
+			wantCode:   "not_found",
+		},
+		{
+			name:       "create",
+			method:     "POST",
+			target:     modulesPath,
+			body:       `{"name":"postgres","custom":{"image":"postgres:18"}}`,
+			wantStatus: 201,
+			wantCode:   "created",
+			check: func(t *testing.T, kubeClient client.Client, header http.Header, _ any) {
+				if header.Get("Location") != modulesPath+"/postgres" {
+					t.Errorf("Location = %q, want the path of the module", header.Get("Location"))
+				}
+				module := &batchv1.Module{}
+				if !forkspacertest.GetObject(t, kubeClient, module, "default", "postgres") || module.Spec.Workspace.Name != "dev" {
+					t.Error("module was not created in the workspace of the path")
+				}
+			},
+		},
+		{
+			name:       "create dry run",
+			method:     "POST",
+			target:     modulesPath + "?dryRun=true",
+			body:       `{"name":"postgres","custom":{"image":"postgres:18"}}`,
+			wantStatus: 201,
+			wantCode:   "created",
+			check: func(t *testing.T, kubeClient client.Client, header http.Header, data any) {
+				if header.Get("Location") != "" || header.Get("ETag") != "" {
+					t.Errorf("header = %v, want neither Location nor ETag", header)
+				}
+				if forkspacertest.Member(data, "name") != "postgres" {
+					t.Errorf("data = %v, want the postgres module", data)
+				}
+				if forkspacertest.GetObject(t, kubeClient, &batchv1.Module{}, "default", "postgres") {
+					t.Error("dry run created the module")
+				}
+			},
+		},
+		{
+			name:   "create with another workspace in the body",
+			method: "POST",
+			target: modulesPath,
+			body: `{"name":"postgres","workspace":{"name":"prod","namespace":"default"},` +
+				`"custom":{"image":"postgres:18"}}`,
+			wantStatus: 400,
+			wantCode:   "body_validation",
+		},
+		{
+			name:       "create already existing",
+			method:     "POST",
+			target:     modulesPath,
+			body:       `{"name":"redis","custom":{"image":"redis:8"}}`,
+			wantStatus: 409,
+			wantCode:   "conflict",
+		},
+		{
+			name:       "replace if match",
+			method:     "PUT",
+			target:     modulePath,
+			header:     map[string]string{"If-Match": `"1"`},
+			body:       `{"hibernated":true}`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, kubeClient client.Client, header http.Header, _ any) {
+				if header.Get("ETag") == `"1"` || header.Get("ETag") == "" {
+					t.Errorf("ETag = %q, want the new resourceVersion", header.Get("ETag"))
+				}
+				wantModuleHibernated(t, kubeClient, true)
+			},
+		},
+		{
+			name:       "replace stale",
+			method:     "PUT",
+			target:     modulePath,
+			header:     map[string]string{"If-Match": `"42"`},
+			body:       `{"hibernated":true}`,
+			wantStatus: 412,
+			wantCode:   "precondition_failed",
+			check: func(t *testing.T, kubeClient client.Client, _ http.Header, _ any) {
+				wantModuleHibernated(t, kubeClient, false)
+			},
+		},
+		{
+			name:       "replace dry run",
+			method:     "PUT",
+			target:     modulePath + "?dryRun=true",
+			body:       `{"hibernated":true}`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, kubeClient client.Client, header http.Header, data any) {
+				if header.Get("ETag") != "" {
+					t.Errorf("ETag = %q, want none", header.Get("ETag"))
+				}
+				if forkspacertest.Member(data, "hibernated") != true {
+					t.Errorf("data = %v, want the replaced module", data)
+				}
+				wantModuleHibernated(t, kubeClient, false)
+			},
+		},
+		{
+			name:       "patch",
+			method:     "PATCH",
+			target:     modulePath,
+			body:       `{"hibernated":true}`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, kubeClient client.Client, _ http.Header, _ any) {
+				wantModuleHibernated(t, kubeClient, true)
+			},
+		},
+		{
+			name:       "JSON patch stale",
+			method:     "PATCH",
+			target:     modulePath,
+			header:     map[string]string{"Content-Type": "application/json-patch+json", "If-Match": `"42"`},
+			body:       `[{"op":"replace","path":"/spec/hibernated","value":true}]`,
+			wantStatus: 412,
+			wantCode:   "precondition_failed",
+		},
+		{
+			name:       "delete",
+			method:     "DELETE",
+			target:     modulePath,
+			wantStatus: 204,
+			check: func(t *testing.T, kubeClient client.Client, _ http.Header, _ any) {
+				if forkspacertest.GetObject(t, kubeClient, &batchv1.Module{}, "default", "redis") {
+					t.Error("module was not deleted")
+				}
+			},
+		},
+		{
+			name:       "delete stale",
+			method:     "DELETE",
+			target:     modulePath,
+			header:     map[string]string{"If-Match": `"42"`},
+			wantStatus: 412,
+			wantCode:   "precondition_failed",
+		},
+		{
+			name:       "delete missing",
+			method:     "DELETE",
+			target:     modulesPath + "/postgres",
+			wantStatus: 404,
+			wantCode:   "not_found",
+		},
+		{
+			name:       "list leaves out modules in other namespaces",
+			method:     "GET",
+			target:     modulesPath,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, _ client.Client, _ http.Header, data any) {
+				items, _ := forkspacertest.Member(data, "items").([]any)
+				if len(items) != 1 || forkspacertest.Member(items[0], "name") != "redis" {
+					t.Errorf("items = %v, want the redis module", items)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, forkspacertest.Objects()...)
+		})
+	}
+}
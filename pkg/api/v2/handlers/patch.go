@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+	"github.com/forkspacer/api-server/pkg/api/validation"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Spec paths a merge patch or JSON patch may change, as JSON pointers. Everything below them may change too.
+var (
+	workspacePatchPaths = []string{
+		"/spec/hibernated",
+		"/spec/autoHibernation",
+		"/spec/managedCluster",
+	}
+	modulePatchPaths = []string{
+		"/spec/hibernated",
+		"/spec/config",
+		"/spec/helm/values",
+		"/spec/helm/chart/repo/version",
+		"/spec/helm/chart/git/revision",
+		"/spec/custom/image",
+	}
+)
+
+// patchErrors rejects a patched object, with errors keyed like validation errors.
+type patchErrors map[string]string
+
+func (e patchErrors) Error() string {
+	return fmt.Sprintf("invalid patched object: %v", map[string]string(e))
+}
+
+// readPatch reads a merge patch or JSON patch body, and rejects one changing anything but paths.
+// It returns nil for a JSON or YAML body, which holds a patch request of the handler instead.
+func readPatch(w http.ResponseWriter, r *http.Request, paths []string) (*forkspacer.PatchIn, error) {
+	contentType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+
+	var patchType types.PatchType
+	switch {
+	case contentType == response.MediaTypeJSON || response.IsYAMLMediaType(contentType):
+		return nil, nil
+	case contentType == string(types.MergePatchType):
+		patchType = types.MergePatchType
+	case contentType == string(types.JSONPatchType):
+		patchType = types.JSONPatchType
+	default:
+		response.JSONUnsopportedMediaType(w, fmt.Sprintf("%s, %s, %s or %s",
+			response.MediaTypeJSON, response.MediaTypeYAML, types.MergePatchType, types.JSONPatchType,
+		))
+		return nil, fmt.Errorf("unsupported media type")
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			response.JSONBodyTooLarge(w, maxBytesErr.Limit)
+			return nil, fmt.Errorf("request body too large: %w", err)
+		}
+
+		response.JSONMalformedJSONBody(w)
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	var path string
+	if patchType == types.MergePatchType {
+		var patch map[string]any
+		if err := json.Unmarshal(data, &patch); err != nil || patch == nil {
+			response.JSONMalformedJSONBody(w)
+			return nil, fmt.Errorf("failed to decode merge patch: %v", err)
+		}
+		path = mergePatchViolation("", patch, paths)
+	} else {
+		var patch []struct {
+			Op   string  `json:"op"`
+			Path string  `json:"path"`
+			From *string `json:"from"`
+		}
+		if err := json.Unmarshal(data, &patch); err != nil {
+			response.JSONMalformedJSONBody(w)
+			return nil, fmt.Errorf("failed to decode JSON patch: %w", err)
+		}
+		for _, operation := range patch {
+			// A move removes its source, other operations only read it
+			if operation.Op == "move" && operation.From != nil && !patchable(*operation.From, paths) {
+				path = *operation.From
+				break
+			}
+			if operation.Op != "test" && !patchable(operation.Path, paths) {
+				path = operation.Path
+				break
+			}
+		}
+	}
+	if path != "" {
+		response.JSONBodyValidationError(w, map[string]string{
+			"patch": fmt.Sprintf("%s cannot be patched, patchable paths are %s", path, strings.Join(paths, ", ")),
+		})
+		return nil, fmt.Errorf("patch changes %s", path)
+	}
+
+	return &forkspacer.PatchIn{Type: patchType, Data: data}, nil
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// mergePatchViolation returns the first path a merge patch changes outside of paths, or "" if there is none.
+// Members of a patch changing an ancestor of a patchable path must be objects, which are merged.
+func mergePatchViolation(prefix string, patch map[string]any, paths []string) string {
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		path := prefix + "/" + pointerEscaper.Replace(key)
+		if patchable(path, paths) {
+			continue
+		}
+
+		member, isObject := patch[key].(map[string]any)
+		isAncestor := slices.ContainsFunc(paths, func(patchPath string) bool {
+			return strings.HasPrefix(patchPath, path+"/")
+		})
+		if !isObject || !isAncestor {
+			return path
+		}
+		if violation := mergePatchViolation(path, member, paths); violation != "" {
+			return violation
+		}
+	}
+
+	return ""
+}
+
+// patchable reports whether path is one of paths or below one.
+func patchable(path string, paths []string) bool {
+	return slices.ContainsFunc(paths, func(patchPath string) bool {
+		return path == patchPath || strings.HasPrefix(path, patchPath+"/")
+	})
+}
+
+// validatePatchedSpec validates a patched spec with the validators of the request fields it is decoded into.
+func validatePatchedSpec(r *http.Request, spec any, requestData any) error {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, requestData); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return patchErrors{"patch": fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type)}
+		}
+		return err
+	}
+
+	return validation.Validate.StructCtx(r.Context(), requestData)
+}
+
+// patchError responds with the error of a patch, which is a service error unless the patched object is invalid.
+func patchError(w http.ResponseWriter, r *http.Request, logger *zap.Logger, message string, err error) {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		response.JSONBodyValidationError(w, validationErrs.Translate(validation.Translator(r.Context())))
+		return
+	}
+
+	var invalid patchErrors
+	if errors.As(err, &invalid) {
+		response.JSONBodyValidationError(w, invalid)
+		return
+	}
+
+	serviceError(w, r, logger, message, err)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/forkspacer/api-server/pkg/api/etag"
+	"github.com/forkspacer/api-server/pkg/api/response"
+	v1handlers "github.com/forkspacer/api-server/pkg/api/v1/handlers"
+	"github.com/forkspacer/api-server/pkg/api/validation"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	"github.com/forkspacer/api-server/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ObjectPath holds the URL parameters addressing an object. Parameters a route lacks are left empty.
+type ObjectPath struct {
+	Namespace  string `json:"namespace" validate:"required,dns1123label"`
+	Workspace  string `json:"workspace" validate:"omitempty,dns1123subdomain"`
+	Module     string `json:"module" validate:"omitempty,dns1123subdomain"`
+	Kubeconfig string `json:"kubeconfig" validate:"omitempty,dns1123subdomain"`
+}
+
+func readObjectPath(w http.ResponseWriter, r *http.Request) (*ObjectPath, error) {
+	objectPath := &ObjectPath{
+		Namespace:  chi.URLParam(r, "namespace"),
+		Workspace:  chi.URLParam(r, "workspace"),
+		Module:     chi.URLParam(r, "module"),
+		Kubeconfig: chi.URLParam(r, "kubeconfig"),
+	}
+
+	if err := validation.URLParamsValidate(r.Context(), w, objectPath); err != nil {
+		return nil, err
+	}
+
+	return objectPath, nil
+}
+
+type ListQuery struct {
+	Limit         *int64              `json:"limit,omitempty" validate:"omitempty,gte=1,lte=250"`
+	ContinueToken *string             `json:"continueToken,omitempty"`
+	Sort          forkspacer.ListSort `json:"-"`
+}
+
+func readListQuery(w http.ResponseWriter, r *http.Request) (*ListQuery, error) {
+	var query = &ListQuery{}
+
+	if r.URL.Query().Has("limit") {
+		qLimit, err := utils.ParseString[int64](r.URL.Query().Get("limit"))
+		if err != nil {
+			response.JSONBadRequest(w, err.Error())
+			return nil, err
+		}
+		query.Limit = &qLimit
+	}
+
+	if r.URL.Query().Has("continueToken") {
+		query.ContinueToken = utils.ToPtr(r.URL.Query().Get("continueToken"))
+	}
+
+	if err := validation.URLParamsValidate(r.Context(), w, query); err != nil {
+		return nil, err
+	}
+
+	if query.Limit == nil {
+		query.Limit = utils.ToPtr[int64](25)
+	}
+
+	sort, err := v1handlers.ReadListSort(w, r)
+	if err != nil {
+		return nil, err
+	}
+	query.Sort = sort
+
+	return query, nil
+}
+
+// matchPath fills an omitted body field from the path, and rejects a body naming another value.
+func matchPath(w http.ResponseWriter, field string, bodyValue **string, pathValue string) error {
+	if *bodyValue == nil {
+		*bodyValue = &pathValue
+		return nil
+	}
+
+	if **bodyValue != pathValue {
+		response.JSONBodyValidationError(w, map[string]string{
+			field: fmt.Sprintf("%s must match the path (%s) or be omitted", field, pathValue),
+		})
+		return fmt.Errorf("%s in body does not match the path", field)
+	}
+
+	return nil
+}
+
+// readIfMatch returns the resourceVersion a write is conditional on, or nil for an unconditional write.
+func readIfMatch(w http.ResponseWriter, r *http.Request) (*string, error) {
+	resourceVersion, err := etag.IfMatch(r)
+	if err != nil {
+		response.JSONBadRequest(w, err.Error())
+		return nil, err
+	}
+
+	return resourceVersion, nil
+}
+
+func deleteOptions(resourceVersion *string, dryRun bool) []client.DeleteOption {
+	opts := v1handlers.DryRunOptions[client.DeleteOption](dryRun)
+	if resourceVersion != nil {
+		opts = append(opts, client.Preconditions{ResourceVersion: resourceVersion})
+	}
+
+	return opts
+}
+
+// createdLocation returns the URL of an object created through a POST to its collection.
+func createdLocation(r *http.Request, name string) string {
+	return path.Join(r.URL.Path, name)
+}
+
+// serviceError logs a failed service call and responds with it.
+// A conflict the API server returns for a conditional delete fails its precondition, and is reported as such.
+func serviceError(w http.ResponseWriter, r *http.Request, logger *zap.Logger, message string, err error) {
+	v1handlers.LogServiceError(r, logger, message, err)
+
+	if apierrors.IsConflict(err) && r.Header.Get("If-Match") != "" {
+		response.JSONPreconditionFailed(w, err.Error())
+		return
+	}
+
+	v1handlers.JSONServiceError(w, err)
+}
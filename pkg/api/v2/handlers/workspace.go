@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/api/etag"
+	"github.com/forkspacer/api-server/pkg/api/response"
+	v1handlers "github.com/forkspacer/api-server/pkg/api/v1/handlers"
+	"github.com/forkspacer/api-server/pkg/api/validation"
+	"github.com/forkspacer/api-server/pkg/logging"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	"github.com/forkspacer/api-server/pkg/utils"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	"go.uber.org/zap"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type WorkspaceHandler struct {
+	logger                     *zap.Logger
+	forkspacerWorkspaceService *forkspacer.ForkspacerWorkspaceService
+	forkspacerModuleService    *forkspacer.ForkspacerModuleService
+}
+
+func NewWorkspaceHandler(
+	logger *zap.Logger,
+	forkspacerWorkspaceService *forkspacer.ForkspacerWorkspaceService,
+	forkspacerModuleService *forkspacer.ForkspacerModuleService,
+) *WorkspaceHandler {
+	return &WorkspaceHandler{logger, forkspacerWorkspaceService, forkspacerModuleService}
+}
+
+type Workspace struct {
+	Name            string                                 `json:"name"`
+	Namespace       string                                 `json:"namespace"`
+	Type            string                                 `json:"type"`
+	From            *v1handlers.WorkspaceResourceReference `json:"from,omitempty"`
+	Connection      v1handlers.WorkspaceConnection         `json:"connection"`
+	Hibernated      bool                                   `json:"hibernated"`
+	AutoHibernation v1handlers.WorkspaceAutoHibernation    `json:"autoHibernation"`
+	ManagedCluster  *v1handlers.ManagedCluster             `json:"managedCluster,omitempty"`
+	Phase           string                                 `json:"phase"`
+	Message         string                                 `json:"message"`
+	CreatedAt       time.Time                              `json:"createdAt,omitzero"`
+	Age             string                                 `json:"age,omitempty"`
+	Labels          map[string]string                      `json:"labels,omitempty"`
+	ModuleCount     *int                                   `json:"moduleCount,omitempty"`
+	StatusUpdatedAt time.Time                              `json:"statusUpdatedAt,omitzero"`
+}
+
+func newWorkspace(workspace *batchv1.Workspace) Workspace {
+	responseData := Workspace{
+		Name:      workspace.Name,
+		Namespace: workspace.Namespace,
+		Type:      string(workspace.Spec.Type),
+		Connection: v1handlers.WorkspaceConnection{
+			Type: string(workspace.Spec.Connection.Type),
+		},
+		Hibernated: workspace.Spec.Hibernated,
+		AutoHibernation: v1handlers.WorkspaceAutoHibernation{
+			Enabled:      workspace.Spec.AutoHibernation.Enabled,
+			Schedule:     workspace.Spec.AutoHibernation.Schedule,
+			WakeSchedule: workspace.Spec.AutoHibernation.WakeSchedule,
+		},
+		Phase:           string(workspace.Status.Phase),
+		CreatedAt:       workspace.CreationTimestamp.Time,
+		Age:             v1handlers.ObjectAge(workspace),
+		Labels:          workspace.Labels,
+		StatusUpdatedAt: v1handlers.StatusUpdatedAt(workspace),
+	}
+
+	if workspace.Spec.From != nil {
+		responseData.From = &v1handlers.WorkspaceResourceReference{
+			Name:      workspace.Spec.From.Name,
+			Namespace: workspace.Spec.From.Namespace,
+		}
+	}
+
+	if secretReference := workspace.Spec.Connection.SecretReference; secretReference != nil {
+		responseData.Connection.Secret = &v1handlers.WorkspaceResourceReference{
+			Name:      secretReference.Name,
+			Namespace: secretReference.Namespace,
+		}
+		if secretReference.Key != "" {
+			responseData.Connection.Key = utils.ToPtr(secretReference.Key)
+		}
+	}
+
+	if managedCluster := workspace.Spec.ManagedCluster; managedCluster != nil {
+		responseData.ManagedCluster = &v1handlers.ManagedCluster{}
+		if managedCluster.Backend != "" {
+			responseData.ManagedCluster.Backend = utils.ToPtr(string(managedCluster.Backend))
+		}
+		if managedCluster.Distro != "" {
+			responseData.ManagedCluster.Distro = utils.ToPtr(managedCluster.Distro)
+		}
+	}
+
+	if workspace.Status.Message != nil {
+		responseData.Message = *workspace.Status.Message
+	}
+
+	return responseData
+}
+
+type ListWorkspacesResponse struct {
+	ContinueToken string `json:"continueToken"`
+	// Items holds Workspaces, restricted to the requested fields if any
+	Items any `json:"items"`
+}
+
+// ListAllHandle lists workspaces across every namespace the API may touch.
+func (h WorkspaceHandler) ListAllHandle(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, nil)
+}
+
+func (h WorkspaceHandler) ListHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	h.list(w, r, &objectPath.Namespace)
+}
+
+func (h WorkspaceHandler) list(w http.ResponseWriter, r *http.Request, namespace *string) {
+	query, err := readListQuery(w, r)
+	if err != nil {
+		return
+	}
+
+	fields, err := v1handlers.ReadListFields[Workspace](w, r)
+	if err != nil {
+		return
+	}
+
+	workspaceList, err := h.forkspacerWorkspaceService.List(
+		r.Context(), namespace, *query.Limit, query.ContinueToken, query.Sort,
+	)
+	if err != nil {
+		serviceError(w, r, h.logger, "failed to list workspaces", err)
+		return
+	}
+
+	var moduleCounts map[forkspacer.ResourceReference]int
+	if v1handlers.IncludesField(fields, "moduleCount") {
+		moduleCounts, err = h.forkspacerModuleService.CountByWorkspace(
+			r.Context(), v1handlers.WorkspaceReferences(workspaceList),
+		)
+		if err != nil {
+			serviceError(w, r, h.logger, "failed to count workspace modules", err)
+			return
+		}
+	}
+
+	items := make([]Workspace, len(workspaceList.Items))
+	for i := range workspaceList.Items {
+		workspace := &workspaceList.Items[i]
+		items[i] = newWorkspace(workspace)
+		if moduleCounts != nil {
+			items[i].ModuleCount = utils.ToPtr(moduleCounts[forkspacer.ResourceReference{
+				Name: workspace.Name, Namespace: workspace.Namespace,
+			}])
+		}
+	}
+
+	projected, err := v1handlers.ProjectFields(items, fields)
+	if err != nil {
+		logging.FromContext(r.Context(), h.logger).Error("failed to project workspace fields", zap.Error(err))
+		response.JSONInternal(w)
+		return
+	}
+
+	responseData := ListWorkspacesResponse{ContinueToken: workspaceList.Continue, Items: projected}
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, responseData))
+}
+
+// CreateHandle creates a workspace in the namespace of the path.
+// The body is the v1 create request, whose namespace may be omitted.
+func (h WorkspaceHandler) CreateHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	dryRun, err := v1handlers.ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
+	var requestData = &v1handlers.CreateWorkspaceRequest{}
+	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
+		return
+	}
+	if err := matchPath(w, "namespace", &requestData.Namespace, objectPath.Namespace); err != nil {
+		return
+	}
+
+	workspace, err := h.forkspacerWorkspaceService.Create(
+		r.Context(), requestData.WorkspaceCreateIn(), v1handlers.DryRunOptions[client.CreateOption](dryRun)...,
+	)
+	if err != nil {
+		serviceError(w, r, h.logger, "failed to create workspace", err)
+		return
+	}
+
+	if !dryRun {
+		w.Header().Set("Location", createdLocation(r, workspace.Name))
+		etag.Set(w, workspace.ResourceVersion)
+	}
+	response.JSONSuccess(w, 201, response.NewJSONSuccess(response.SuccessCodes.Created, newWorkspace(workspace)))
+}
+
+func (h WorkspaceHandler) GetHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	workspace, err := h.forkspacerWorkspaceService.Get(r.Context(), objectPath.Workspace, &objectPath.Namespace)
+	if err != nil {
+		serviceError(w, r, h.logger, "failed to get workspace", err)
+		return
+	}
+
+	if etag.NoneMatch(r, workspace.ResourceVersion) {
+		etag.NotModified(w, workspace.ResourceVersion)
+		return
+	}
+
+	etag.Set(w, workspace.ResourceVersion)
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, newWorkspace(workspace)))
+}
+
+// ReplaceWorkspaceRequest holds every workspace setting that can change after creation.
+// Omitted settings are reset, except for the managed cluster which is kept when omitted.
+type ReplaceWorkspaceRequest struct {
+	Hibernated      bool                                 `json:"hibernated"`
+	AutoHibernation *v1handlers.WorkspaceAutoHibernation `json:"autoHibernation,omitempty"`
+	ManagedCluster  *v1handlers.ManagedCluster           `json:"managedCluster,omitempty"`
+}
+
+func (h WorkspaceHandler) ReplaceHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	resourceVersion, err := readIfMatch(w, r)
+	if err != nil {
+		return
+	}
+
+	dryRun, err := v1handlers.ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
+	var requestData = &ReplaceWorkspaceRequest{}
+	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
+		return
+	}
+
+	autoHibernation := requestData.AutoHibernation
+	if autoHibernation == nil {
+		autoHibernation = &v1handlers.WorkspaceAutoHibernation{}
+	}
+
+	h.update(w, r, forkspacer.WorkspaceUpdateIn{
+		Name:            objectPath.Workspace,
+		Namespace:       &objectPath.Namespace,
+		Hibernated:      &requestData.Hibernated,
+		AutoHibernation: autoHibernation.WorkspaceAutoHibernationIn(),
+		ManagedCluster:  requestData.ManagedCluster.ManagedClusterIn(),
+		ResourceVersion: resourceVersion,
+	}, dryRun)
+}
+
+// PatchWorkspaceRequest changes only the settings it sets.
+type PatchWorkspaceRequest struct {
+	Hibernated      *bool                                `json:"hibernated,omitempty"`
+	AutoHibernation *v1handlers.WorkspaceAutoHibernation `json:"autoHibernation,omitempty"`
+	ManagedCluster  *v1handlers.ManagedCluster           `json:"managedCluster,omitempty"`
+}
+
+func (h WorkspaceHandler) PatchHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	resourceVersion, err := readIfMatch(w, r)
+	if err != nil {
+		return
+	}
+
+	dryRun, err := v1handlers.ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
+	patchIn, err := readPatch(w, r, workspacePatchPaths)
+	if err != nil {
+		return
+	}
+	if patchIn != nil {
+		patchIn.ResourceVersion = resourceVersion
+		h.patch(w, r, objectPath, *patchIn, dryRun)
+		return
+	}
+
+	var requestData = &PatchWorkspaceRequest{}
+	if err := validation.JSONBodyReadAndValidate(w, r, requestData); err != nil {
+		return
+	}
+
+	h.update(w, r, forkspacer.WorkspaceUpdateIn{
+		Name:            objectPath.Workspace,
+		Namespace:       &objectPath.Namespace,
+		Hibernated:      requestData.Hibernated,
+		AutoHibernation: requestData.AutoHibernation.WorkspaceAutoHibernationIn(),
+		ManagedCluster:  requestData.ManagedCluster.ManagedClusterIn(),
+		ResourceVersion: resourceVersion,
+	}, dryRun)
+}
+
+// patch applies a merge patch or JSON patch, after validating the patched settings like a replace request.
+func (h WorkspaceHandler) patch(
+	w http.ResponseWriter, r *http.Request, objectPath *ObjectPath, patchIn forkspacer.PatchIn, dryRun bool,
+) {
+	workspace, err := h.forkspacerWorkspaceService.Patch(
+		r.Context(), objectPath.Workspace, &objectPath.Namespace, patchIn,
+		func(workspace *batchv1.Workspace) error {
+			return validatePatchedSpec(r, workspace.Spec, &ReplaceWorkspaceRequest{})
+		},
+		v1handlers.DryRunOptions[client.PatchOption](dryRun)...,
+	)
+	if err != nil {
+		patchError(w, r, h.logger, "failed to patch workspace", err)
+		return
+	}
+
+	if !dryRun {
+		etag.Set(w, workspace.ResourceVersion)
+	}
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, newWorkspace(workspace)))
+}
+
+func (h WorkspaceHandler) update(
+	w http.ResponseWriter, r *http.Request, updateIn forkspacer.WorkspaceUpdateIn, dryRun bool,
+) {
+	workspace, err := h.forkspacerWorkspaceService.Update(
+		r.Context(), updateIn, v1handlers.DryRunOptions[client.UpdateOption](dryRun)...,
+	)
+	if err != nil {
+		serviceError(w, r, h.logger, "failed to update workspace", err)
+		return
+	}
+
+	if !dryRun {
+		etag.Set(w, workspace.ResourceVersion)
+	}
+	response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, newWorkspace(workspace)))
+}
+
+func (h WorkspaceHandler) DeleteHandle(w http.ResponseWriter, r *http.Request) {
+	objectPath, err := readObjectPath(w, r)
+	if err != nil {
+		return
+	}
+
+	resourceVersion, err := readIfMatch(w, r)
+	if err != nil {
+		return
+	}
+
+	dryRun, err := v1handlers.ReadDryRun(w, r)
+	if err != nil {
+		return
+	}
+
+	if err := h.forkspacerWorkspaceService.Delete(
+		r.Context(), objectPath.Workspace, &objectPath.Namespace, deleteOptions(resourceVersion, dryRun)...,
+	); err != nil {
+		serviceError(w, r, h.logger, "failed to delete workspace", err)
+		return
+	}
+
+	response.JSONDeleted(w)
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/forkspacer/api-server/pkg/services/forkspacer/forkspacertest"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// wantHibernated checks whether the stored workspace named name in the default namespace is hibernated.
+func wantHibernated(t *testing.T, kubeClient client.Client, name string, want bool) {
+	t.Helper()
+
+	workspace := &batchv1.Workspace{}
+	if forkspacertest.GetObject(t, kubeClient, workspace, "default", name); workspace.Spec.Hibernated != want {
+		t.Errorf("hibernated = %v, want %v", workspace.Spec.Hibernated, want)
+	}
+}
+
+func TestWorkspaceHandlers(t *testing.T) {
+	const workspacePath = "/namespaces/default/workspaces/dev"
+
+	// staleGets counts the reads of a stale replace, which fails without being retried
+	staleGets := 0
+
+	tests := []handlerTest{
+		{
+			name:       "get",
+			method:     "GET",
+			target:     workspacePath,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, _ client.Client, header http.Header, data any) {
+				if header.Get("ETag") != `"1"` {
+					t.Errorf("ETag = %q, want \"1\"", header.Get("ETag"))
+				}
+				if forkspacertest.Member(data, "name") != "dev" || forkspacertest.Member(data, "type") != "kubernetes" {
+					t.Errorf("data = %v, want the dev workspace", data)
+				}
+			},
+		},
+		{
+			name:       "get unmodified",
+			method:     "GET",
+			target:     workspacePath,
+			header:     map[string]string{"If-None-Match": `"1"`},
+			wantStatus: 304,
+		},
+		{
+			name:       "get missing",
+			method:     "GET",
+			target:     "/namespaces/default/workspaces/prod",
+			wantStatus: 404,
+			wantCode:   "not_found",
+		},
+		{
+			name:       "get in a namespace outside the policy",
+			method:     "GET",
+			target:     "/namespaces/kube-system/workspaces/dev",
+			wantStatus: 403,
+			wantCode:   "forbidden",
+		},
+		{
+			name:       "create",
+			method:     "POST",
+			target:     "/namespaces/default/workspaces",
+			body:       `{"name":"staging","connection":{"type":"in-cluster"}}`,
+			wantStatus: 201,
+			wantCode:   "created",
+			check: func(t *testing.T, _ client.Client, header http.Header, _ any) {
+				if header.Get("Location") != "/namespaces/default/workspaces/staging" {
+					t.Errorf("Location = %q, want the path of the workspace", header.Get("Location"))
+				}
+				if header.Get("ETag") == "" {
+					t.Error("ETag is not set")
+				}
+			},
+		},
+		{
+			name:       "create dry run",
+			method:     "POST",
+			target:     "/namespaces/default/workspaces?dryRun=true",
+			body:       `{"name":"staging","connection":{"type":"in-cluster"}}`,
+			wantStatus: 201,
+			wantCode:   "created",
+			check: func(t *testing.T, kubeClient client.Client, header http.Header, data any) {
+				if header.Get("Location") != "" || header.Get("ETag") != "" {
+					t.Errorf("header = %v, want neither Location nor ETag", header)
+				}
+				if forkspacertest.Member(data, "type") != "kubernetes" {
+					t.Errorf("data = %v, want the defaulted workspace", data)
+				}
+				if forkspacertest.GetObject(t, kubeClient, &batchv1.Workspace{}, "default", "staging") {
+					t.Error("dry run created the workspace")
+				}
+			},
+		},
+		{
+			name:       "create with another namespace in the body",
+			method:     "POST",
+			target:     "/namespaces/default/workspaces",
+			body:       `{"name":"staging","namespace":"team-a","connection":{"type":"in-cluster"}}`,
+			wantStatus: 400,
+			wantCode:   "body_validation",
+		},
+		{
+			name:       "replace",
+			method:     "PUT",
+			target:     workspacePath,
+			body:       `{"hibernated":true}`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, kubeClient client.Client, header http.Header, _ any) {
+				if header.Get("ETag") == `"1"` || header.Get("ETag") == "" {
+					t.Errorf("ETag = %q, want the new resourceVersion", header.Get("ETag"))
+				}
+				wantHibernated(t, kubeClient, "dev", true)
+			},
+		},
+		{
+			name:       "replace if match",
+			method:     "PUT",
+			target:     workspacePath,
+			header:     map[string]string{"If-Match": `"1"`},
+			body:       `{"hibernated":true}`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, kubeClient client.Client, _ http.Header, _ any) {
+				wantHibernated(t, kubeClient, "dev", true)
+			},
+		},
+		{
+			name:       "replace stale",
+			method:     "PUT",
+			target:     workspacePath,
+			header:     map[string]string{"If-Match": `"42"`},
+			body:       `{"hibernated":true}`,
+			wantStatus: 412,
+			wantCode:   "precondition_failed",
+			check: func(t *testing.T, kubeClient client.Client, _ http.Header, _ any) {
+				wantHibernated(t, kubeClient, "dev", false)
+			},
+		},
+		{
+			name:   "replace stale is not retried",
+			method: "PUT",
+			target: workspacePath,
+			header: map[string]string{"If-Match": `"42"`},
+			body:   `{"hibernated":true}`,
+			funcs: interceptor.Funcs{
+				Get: func(
+					ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption,
+				) error {
+					staleGets++
+					return c.Get(ctx, key, obj, opts...)
+				},
+			},
+			wantStatus: 412,
+			wantCode:   "precondition_failed",
+			check: func(t *testing.T, _ client.Client, _ http.Header, _ any) {
+				if staleGets != 1 {
+					t.Errorf("workspace was read %d times, want once", staleGets)
+				}
+			},
+		},
+		{
+			name:       "replace with a weak entity tag",
+			method:     "PUT",
+			target:     workspacePath,
+			header:     map[string]string{"If-Match": `W/"1"`},
+			body:       `{"hibernated":true}`,
+			wantStatus: 400,
+			wantCode:   "bad_request",
+		},
+		{
+			name:   "replace conflict",
+			method: "PUT",
+			target: workspacePath,
+			body:   `{"hibernated":true}`,
+			funcs: interceptor.Funcs{
+				Update: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.UpdateOption) error {
+					return apierrors.NewConflict(
+						batchv1.GroupVersion.WithResource("workspaces").GroupResource(), obj.GetName(),
+						errors.New("the object has been modified"),
+					)
+				},
+			},
+			wantStatus: 409,
+			wantCode:   "conflict",
+		},
+		{
+			name:       "replace dry run",
+			method:     "PUT",
+			target:     workspacePath + "?dryRun=true",
+			body:       `{"hibernated":true}`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, kubeClient client.Client, header http.Header, data any) {
+				if header.Get("ETag") != "" {
+					t.Errorf("ETag = %q, want none", header.Get("ETag"))
+				}
+				if forkspacertest.Member(data, "hibernated") != true {
+					t.Errorf("data = %v, want the replaced workspace", data)
+				}
+				wantHibernated(t, kubeClient, "dev", false)
+			},
+		},
+		{
+			name:       "merge patch",
+			method:     "PATCH",
+			target:     workspacePath,
+			header:     map[string]string{"Content-Type": "application/merge-patch+json", "If-Match": `"1"`},
+			body:       `{"spec":{"hibernated":true}}`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, kubeClient client.Client, _ http.Header, _ any) {
+				wantHibernated(t, kubeClient, "dev", true)
+			},
+		},
+		{
+			name:       "merge patch stale",
+			method:     "PATCH",
+			target:     workspacePath,
+			header:     map[string]string{"Content-Type": "application/merge-patch+json", "If-Match": `"42"`},
+			body:       `{"spec":{"hibernated":true}}`,
+			wantStatus: 412,
+			wantCode:   "precondition_failed",
+		},
+		{
+			name:       "JSON patch dry run",
+			method:     "PATCH",
+			target:     workspacePath + "?dryRun=true",
+			header:     map[string]string{"Content-Type": "application/json-patch+json"},
+			body:       `[{"op":"replace","path":"/spec/hibernated","value":true}]`,
+			wantStatus: 200,
+			wantCode:   "ok",
+			// The in-memory backend returns dry run patches unapplied, so only the stored object is checked
+			check: func(t *testing.T, kubeClient client.Client, _ http.Header, _ any) {
+				wantHibernated(t, kubeClient, "dev", false)
+			},
+		},
+		{
+			name:       "JSON patch of a setting that cannot change",
+			method:     "PATCH",
+			target:     workspacePath,
+			header:     map[string]string{"Content-Type": "application/json-patch+json"},
+			body:       `[{"op":"replace","path":"/spec/type","value":"managed"}]`,
+			wantStatus: 400,
+			wantCode:   "body_validation",
+		},
+		{
+			name:       "delete",
+			method:     "DELETE",
+			target:     workspacePath,
+			header:     map[string]string{"If-Match": `"1"`},
+			wantStatus: 204,
+			check: func(t *testing.T, kubeClient client.Client, _ http.Header, _ any) {
+				if forkspacertest.GetObject(t, kubeClient, &batchv1.Workspace{}, "default", "dev") {
+					t.Error("workspace was not deleted")
+				}
+			},
+		},
+		{
+			name:       "delete stale",
+			method:     "DELETE",
+			target:     workspacePath,
+			header:     map[string]string{"If-Match": `"42"`},
+			wantStatus: 412,
+			wantCode:   "precondition_failed",
+			check: func(t *testing.T, kubeClient client.Client, _ http.Header, _ any) {
+				if !forkspacertest.GetObject(t, kubeClient, &batchv1.Workspace{}, "default", "dev") {
+					t.Error("workspace was deleted")
+				}
+			},
+		},
+		{
+			name:       "delete dry run",
+			method:     "DELETE",
+			target:     workspacePath + "?dryRun=true",
+			wantStatus: 204,
+			check: func(t *testing.T, kubeClient client.Client, _ http.Header, _ any) {
+				if !forkspacertest.GetObject(t, kubeClient, &batchv1.Workspace{}, "default", "dev") {
+					t.Error("dry run deleted the workspace")
+				}
+			},
+		},
+		{
+			name:       "list counts modules in every namespace",
+			method:     "GET",
+			target:     "/namespaces/default/workspaces",
+			wantStatus: 200,
+			wantCode:   "ok",
+			check: func(t *testing.T, _ client.Client, _ http.Header, data any) {
+				items, _ := forkspacertest.Member(data, "items").([]any)
+				if len(items) != 1 || forkspacertest.Member(items[0], "moduleCount") != 2.0 {
+					t.Errorf("items = %v, want dev with 2 modules", items)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, forkspacertest.Objects()...)
+		})
+	}
+}
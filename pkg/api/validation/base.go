@@ -6,10 +6,14 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/go-playground/locales/de"
 	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/fr"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	de_translations "github.com/go-playground/validator/v10/translations/de"
 	en_translations "github.com/go-playground/validator/v10/translations/en"
+	fr_translations "github.com/go-playground/validator/v10/translations/fr"
 	"go.yaml.in/yaml/v3"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -39,12 +43,13 @@ func init() {
 	}
 }
 
+// GetTranslation returns the translator of lang, English if lang has no translations.
 func GetTranslation(lang string) ut.Translator {
-	if lang == "" {
-		return langToTrans["en"]
+	if trans, ok := langToTrans[lang]; ok {
+		return trans
 	}
 
-	return langToTrans[lang]
+	return langToTrans["en"]
 }
 
 func registerCustomValidations() error {
@@ -71,84 +76,65 @@ func registerCustomValidations() error {
 	return nil
 }
 
+// customTranslations holds the messages of the custom validation tags, by tag and language.
+var customTranslations = map[string]map[string]string{ //nolint:lll
+	"dns1123subdomain": {
+		"en": "{0} must be a valid DNS subdomain (RFC 1123): lowercase alphanumeric characters, '-' or '.', max 253 characters",
+		"de": "{0} muss eine gültige DNS-Subdomain (RFC 1123) sein: Kleinbuchstaben, Ziffern, '-' oder '.', höchstens 253 Zeichen",
+		"fr": "{0} doit être un sous-domaine DNS valide (RFC 1123) : lettres minuscules, chiffres, '-' ou '.', 253 caractères au maximum",
+	},
+	"dns1123label": {
+		"en": "{0} must be a valid DNS label (RFC 1123): lowercase alphanumeric characters or '-', max 63 characters",
+		"de": "{0} muss ein gültiges DNS-Label (RFC 1123) sein: Kleinbuchstaben, Ziffern oder '-', höchstens 63 Zeichen",
+		"fr": "{0} doit être un label DNS valide (RFC 1123) : lettres minuscules, chiffres ou '-', 63 caractères au maximum",
+	},
+	"dns1035label": {
+		"en": "{0} must be a valid DNS label (RFC 1035): must start with a lowercase letter, followed by lowercase alphanumeric characters or '-', max 63 characters",
+		"de": "{0} muss ein gültiges DNS-Label (RFC 1035) sein: ein Kleinbuchstabe, gefolgt von Kleinbuchstaben, Ziffern oder '-', höchstens 63 Zeichen",
+		"fr": "{0} doit être un label DNS valide (RFC 1035) : une lettre minuscule, suivie de lettres minuscules, chiffres ou '-', 63 caractères au maximum",
+	},
+	"kubeconfig": {
+		"en": "{0} must be a valid kubeconfig file in YAML format with required fields (clusters, contexts, users)",
+		"de": "{0} muss eine gültige kubeconfig-Datei im YAML-Format mit den Pflichtfeldern (clusters, contexts, users) sein",
+		"fr": "{0} doit être un fichier kubeconfig valide au format YAML avec les champs requis (clusters, contexts, users)",
+	},
+	"yaml": {
+		"en": "{0} must be a valid YAML file",
+		"de": "{0} muss eine gültige YAML-Datei sein",
+		"fr": "{0} doit être un fichier YAML valide",
+	},
+}
+
 func registerTranslations() error {
 	enLocale := en.New()
 
-	uni := ut.New(enLocale, enLocale)
-
-	enTrans, _ := uni.GetTranslator("en")
-
-	langToTrans = map[string]ut.Translator{
-		"en": enTrans,
-	}
-
-	if err := en_translations.RegisterDefaultTranslations(Validate, enTrans); err != nil {
-		return fmt.Errorf("failed to register default English translations: %w", err)
-	}
-
-	if err := Validate.RegisterTranslation("dns1123subdomain", enTrans, func(ut ut.Translator) error {
-		return ut.Add(
-			"dns1123subdomain",
-			"{0} must be a valid DNS subdomain (RFC 1123): lowercase alphanumeric characters, '-' or '.', max 253 characters",
-			true,
-		)
-	}, func(ut ut.Translator, fe validator.FieldError) string {
-		t, _ := ut.T("dns1123subdomain", fe.Field())
-		return t
-	}); err != nil {
-		return err
-	}
+	uni := ut.New(enLocale, enLocale, de.New(), fr.New())
 
-	if err := Validate.RegisterTranslation("dns1123label", enTrans, func(ut ut.Translator) error {
-		return ut.Add(
-			"dns1123label",
-			"{0} must be a valid DNS label (RFC 1123): lowercase alphanumeric characters or '-', max 63 characters",
-			true,
-		)
-	}, func(ut ut.Translator, fe validator.FieldError) string {
-		t, _ := ut.T("dns1123label", fe.Field())
-		return t
-	}); err != nil {
-		return err
+	defaultTranslations := map[string]func(*validator.Validate, ut.Translator) error{
+		"en": en_translations.RegisterDefaultTranslations,
+		"de": de_translations.RegisterDefaultTranslations,
+		"fr": fr_translations.RegisterDefaultTranslations,
 	}
 
-	if err := Validate.RegisterTranslation("dns1035label", enTrans, func(ut ut.Translator) error {
-		return ut.Add(
-			"dns1035label",
-			"{0} must be a valid DNS label (RFC 1035): must start with a lowercase letter, followed by lowercase alphanumeric characters or '-', max 63 characters", //nolint:lll
-			true,
-		)
-	}, func(ut ut.Translator, fe validator.FieldError) string {
-		t, _ := ut.T("dns1035label", fe.Field())
-		return t
-	}); err != nil {
-		return err
-	}
+	langToTrans = map[string]ut.Translator{}
+	for _, lang := range languages {
+		trans, _ := uni.GetTranslator(lang.String())
+		langToTrans[lang.String()] = trans
 
-	if err := Validate.RegisterTranslation("kubeconfig", enTrans, func(ut ut.Translator) error {
-		return ut.Add(
-			"kubeconfig",
-			"{0} must be a valid kubeconfig file in YAML format with required fields (clusters, contexts, users)",
-			true,
-		)
-	}, func(ut ut.Translator, fe validator.FieldError) string {
-		t, _ := ut.T("kubeconfig", fe.Field())
-		return t
-	}); err != nil {
-		return err
-	}
+		if err := defaultTranslations[lang.String()](Validate, trans); err != nil {
+			return fmt.Errorf("failed to register default %s translations: %w", lang, err)
+		}
 
-	if err := Validate.RegisterTranslation("yaml", enTrans, func(ut ut.Translator) error {
-		return ut.Add(
-			"yaml",
-			"{0} must be a valid YAML file",
-			true,
-		)
-	}, func(ut ut.Translator, fe validator.FieldError) string {
-		t, _ := ut.T("yaml", fe.Field())
-		return t
-	}); err != nil {
-		return err
+		for tag, messages := range customTranslations {
+			if err := Validate.RegisterTranslation(tag, trans, func(ut ut.Translator) error {
+				return ut.Add(tag, messages[lang.String()], true)
+			}, func(ut ut.Translator, fe validator.FieldError) string {
+				t, _ := ut.T(tag, fe.Field())
+				return t
+			}); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -20,7 +20,7 @@ func FormDataBodyValidate(w http.ResponseWriter, r *http.Request, structData any
 	if err := Validate.StructCtx(r.Context(), structData); err != nil {
 		switch errs := err.(type) {
 		case validator.ValidationErrors:
-			response.JSONBodyValidationError(w, errs.Translate(GetTranslation("en")))
+			response.JSONBodyValidationError(w, errs.Translate(Translator(r.Context())))
 			return fmt.Errorf("request body validation failed: %w", errs)
 		default:
 			response.JSONInternal(w)
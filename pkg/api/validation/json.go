@@ -2,7 +2,9 @@ package validation
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
@@ -11,22 +13,53 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
+// JSONBodyReadAndValidate decodes a JSON or YAML request body into structData and validates it.
+// Errors of a YAML body carry the line of the field at fault.
 func JSONBodyReadAndValidate(w http.ResponseWriter, r *http.Request, structData any) error {
 	contentType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
-	if contentType != "application/json" {
-		response.JSONUnsopportedMediaType(w, "application/json")
+	if contentType != response.MediaTypeJSON && !response.IsYAMLMediaType(contentType) {
+		response.JSONUnsopportedMediaType(w, response.MediaTypeJSON+" or "+response.MediaTypeYAML)
 		return fmt.Errorf("unsupported media type")
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(structData); err != nil {
-		response.JSONMalformedJSONBody(w)
-		return fmt.Errorf("failed to decode request body: %w", err)
+	var body *yamlBody
+	if contentType == response.MediaTypeJSON {
+		if err := json.NewDecoder(r.Body).Decode(structData); err != nil {
+			if bodyTooLarge(w, err) {
+				return fmt.Errorf("request body too large: %w", err)
+			}
+
+			response.JSONMalformedJSONBody(w)
+			return fmt.Errorf("failed to decode request body: %w", err)
+		}
+	} else {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			if bodyTooLarge(w, err) {
+				return fmt.Errorf("request body too large: %w", err)
+			}
+
+			response.JSONMalformedYAMLBody(w, "failed to read request body")
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		if body, err = readYAMLBody(data); err == nil {
+			err = body.decode(structData)
+		}
+		if err != nil {
+			response.JSONMalformedYAMLBody(w, err.Error())
+			return fmt.Errorf("failed to decode request body: %w", err)
+		}
 	}
 
 	if err := Validate.StructCtx(r.Context(), structData); err != nil {
 		switch errs := err.(type) {
 		case validator.ValidationErrors:
-			response.JSONBodyValidationError(w, errs.Translate(GetTranslation("en")))
+			if body != nil {
+				response.JSONBodyValidationError(w, body.translate(errs, Translator(r.Context())))
+			} else {
+				response.JSONBodyValidationError(w, errs.Translate(Translator(r.Context())))
+			}
 			return fmt.Errorf("request body validation failed: %w", errs)
 		default:
 			response.JSONInternal(w)
@@ -36,3 +69,14 @@ func JSONBodyReadAndValidate(w http.ResponseWriter, r *http.Request, structData
 
 	return nil
 }
+
+// bodyTooLarge responds with 413 if err comes from reading past the body limit.
+func bodyTooLarge(w http.ResponseWriter, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return false
+	}
+
+	response.JSONBodyTooLarge(w, maxBytesErr.Limit)
+	return true
+}
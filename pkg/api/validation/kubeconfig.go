@@ -0,0 +1,140 @@
+package validation
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// KubeconfigError describes the kubeconfig entry rejected by strict validation.
+type KubeconfigError struct {
+	Entry  string
+	Reason string
+}
+
+func (e *KubeconfigError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Entry, e.Reason)
+}
+
+// SanitizeKubeconfig strictly validates a kubeconfig and returns a rewritten copy that is safe
+// to hand over to the operator.
+//
+// The kubeconfig is minified to its selected context. Exec credential plugins, auth providers
+// and file references that are not backed by embedded data are rejected with a *KubeconfigError
+// naming the offending entry. File references whose data is already embedded are dropped.
+func SanitizeKubeconfig(kubeconfigContent []byte) ([]byte, error) {
+	config, err := clientcmd.Load(kubeconfigContent)
+	if err != nil {
+		return nil, &KubeconfigError{Entry: "kubeconfig", Reason: err.Error()}
+	}
+
+	if config.CurrentContext == "" {
+		if len(config.Contexts) != 1 {
+			return nil, &KubeconfigError{
+				Entry:  "current-context",
+				Reason: "must be set when the kubeconfig does not have exactly one context",
+			}
+		}
+		for name := range config.Contexts {
+			config.CurrentContext = name
+		}
+	}
+
+	selectedContext, exists := config.Contexts[config.CurrentContext]
+	if !exists {
+		return nil, &KubeconfigError{Entry: "current-context", Reason: "refers to a context that does not exist"}
+	}
+	if _, exists := config.Clusters[selectedContext.Cluster]; !exists {
+		return nil, &KubeconfigError{
+			Entry:  fmt.Sprintf("contexts[%s].cluster", config.CurrentContext),
+			Reason: "refers to a cluster that does not exist",
+		}
+	}
+	if _, exists := config.AuthInfos[selectedContext.AuthInfo]; !exists {
+		return nil, &KubeconfigError{
+			Entry:  fmt.Sprintf("contexts[%s].user", config.CurrentContext),
+			Reason: "refers to a user that does not exist",
+		}
+	}
+
+	if err := clientcmdapi.MinifyConfig(config); err != nil {
+		return nil, &KubeconfigError{Entry: "kubeconfig", Reason: err.Error()}
+	}
+
+	for name, cluster := range config.Clusters {
+		if err := sanitizeKubeconfigCluster(name, cluster); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, authInfo := range config.AuthInfos {
+		if err := sanitizeKubeconfigAuthInfo(name, authInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	return clientcmd.Write(*config)
+}
+
+func sanitizeKubeconfigCluster(name string, cluster *clientcmdapi.Cluster) error {
+	if cluster.CertificateAuthority != "" {
+		if len(cluster.CertificateAuthorityData) == 0 {
+			return &KubeconfigError{
+				Entry:  fmt.Sprintf("clusters[%s].certificate-authority", name),
+				Reason: "file references are not allowed, embed it as certificate-authority-data",
+			}
+		}
+		cluster.CertificateAuthority = ""
+	}
+
+	return nil
+}
+
+func sanitizeKubeconfigAuthInfo(name string, authInfo *clientcmdapi.AuthInfo) error {
+	if authInfo.Exec != nil {
+		return &KubeconfigError{
+			Entry:  fmt.Sprintf("users[%s].exec", name),
+			Reason: "exec credential plugins are not allowed",
+		}
+	}
+
+	if authInfo.AuthProvider != nil {
+		return &KubeconfigError{
+			Entry:  fmt.Sprintf("users[%s].auth-provider", name),
+			Reason: "auth providers are not allowed",
+		}
+	}
+
+	if authInfo.ClientCertificate != "" {
+		if len(authInfo.ClientCertificateData) == 0 {
+			return &KubeconfigError{
+				Entry:  fmt.Sprintf("users[%s].client-certificate", name),
+				Reason: "file references are not allowed, embed it as client-certificate-data",
+			}
+		}
+		authInfo.ClientCertificate = ""
+	}
+
+	if authInfo.ClientKey != "" {
+		if len(authInfo.ClientKeyData) == 0 {
+			return &KubeconfigError{
+				Entry:  fmt.Sprintf("users[%s].client-key", name),
+				Reason: "file references are not allowed, embed it as client-key-data",
+			}
+		}
+		authInfo.ClientKey = ""
+	}
+
+	if authInfo.TokenFile != "" {
+		if authInfo.Token == "" {
+			return &KubeconfigError{
+				Entry:  fmt.Sprintf("users[%s].tokenFile", name),
+				Reason: "file references are not allowed, embed it as token",
+			}
+		}
+		authInfo.TokenFile = ""
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package validation
+
+import (
+	"context"
+
+	ut "github.com/go-playground/universal-translator"
+	"golang.org/x/text/language"
+)
+
+// languages validation messages are translated to. The first one is used for any other language.
+var languages = []language.Tag{language.English, language.German, language.French}
+
+var languageMatcher = language.NewMatcher(languages)
+
+type languageContextKey struct{}
+
+// PreferredLanguage returns the language of the validation messages for a request with the given
+// Accept-Language header. Regional variants such as de-CH get the messages of their base language.
+func PreferredLanguage(acceptLanguage string) string {
+	_, index := language.MatchStrings(languageMatcher, acceptLanguage)
+	return languages[index].String()
+}
+
+// WithLanguage stores the language validation messages are translated to for a request.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageContextKey{}, lang)
+}
+
+// Translator returns the translator of the language stored in ctx, English if there is none.
+func Translator(ctx context.Context) ut.Translator {
+	lang, _ := ctx.Value(languageContextKey{}).(string)
+	return GetTranslation(lang)
+}
@@ -14,7 +14,7 @@ func URLParamsValidate(ctx context.Context, w http.ResponseWriter, structData an
 	if err := Validate.StructCtx(ctx, structData); err != nil {
 		switch errs := err.(type) {
 		case validator.ValidationErrors:
-			response.JSONQueryValidationError(w, errs.Translate(GetTranslation("en")))
+			response.JSONQueryValidationError(w, errs.Translate(Translator(ctx)))
 			return fmt.Errorf("request query validation failed: %w", errs)
 		default:
 			response.JSONInternal(w)
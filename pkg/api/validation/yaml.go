@@ -0,0 +1,157 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"go.yaml.in/yaml/v3"
+)
+
+// yamlBody is a YAML request body. It keeps the nodes of the document to tell the line of a field.
+type yamlBody struct {
+	document yaml.Node
+}
+
+// readYAMLBody reads a body holding a single YAML document. Errors name the line at fault when there is one.
+func readYAMLBody(data []byte) (*yamlBody, error) {
+	body := &yamlBody{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&body.document); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("body is empty")
+		}
+		return nil, err
+	}
+
+	var next yaml.Node
+	if err := decoder.Decode(&next); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("line %d: body must hold a single YAML document", next.Line)
+	}
+
+	if err := checkMappingKeys(&body.document); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// checkMappingKeys rejects mapping keys that are not strings, which JSON has no counterpart for.
+func checkMappingKeys(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Tag != "!!str" && key.Tag != "!!merge" {
+				return fmt.Errorf("line %d: mapping key %s must be a string", key.Line, key.Value)
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		if err := checkMappingKeys(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode decodes the body into structData through its JSON encoding, so that structData is decoded with
+// the same field names as from a JSON body.
+func (b *yamlBody) decode(structData any) error {
+	var value any
+	if err := b.document.Decode(&value); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, structData); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return fmt.Errorf(
+				"line %d: %s must be of type %s", b.line(strings.Split(typeErr.Field, ".")), typeErr.Field, typeErr.Type,
+			)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// translate translates validation errors, prefixing each with the line of its field.
+// A missing field is reported at the line of the object it is missing from.
+func (b *yamlBody) translate(errs validator.ValidationErrors, trans ut.Translator) map[string]string {
+	translations := errs.Translate(trans)
+	for _, fieldErr := range errs {
+		namespace := fieldErr.Namespace()
+		// The namespace starts with the name of the request struct, which is the document itself
+		_, fieldPath, _ := strings.Cut(namespace, ".")
+		translations[namespace] = fmt.Sprintf("line %d: %s", b.line(namespacePath(fieldPath)), translations[namespace])
+	}
+	return translations
+}
+
+// line returns the line of the node at path, or of its deepest ancestor in the document when it is missing.
+func (b *yamlBody) line(path []string) int {
+	node := &b.document
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	for _, segment := range path {
+		child := childNode(node, segment)
+		if child == nil {
+			break
+		}
+		node = child
+	}
+
+	return node.Line
+}
+
+func childNode(node *yaml.Node, segment string) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == segment {
+				return node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if index, err := strconv.Atoi(segment); err == nil && index >= 0 && index < len(node.Content) {
+			return node.Content[index]
+		}
+	}
+
+	return nil
+}
+
+// namespacePath splits a validator field namespace such as spec.items[0].name into its path segments.
+func namespacePath(namespace string) []string {
+	var path []string
+	for field := range strings.SplitSeq(namespace, ".") {
+		name, indexes, _ := strings.Cut(field, "[")
+		path = append(path, name)
+		if indexes != "" {
+			path = append(path, strings.Split(strings.TrimSuffix(indexes, "]"), "][")...)
+		}
+	}
+	return path
+}
@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	"go.uber.org/zap"
+)
+
+// ObjectReference identifies the object targeted by an audited request.
+type ObjectReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+}
+
+var (
+	WorkspaceObject = ObjectReference{APIVersion: batchv1.GroupVersion.String(), Kind: "Workspace"}
+	ModuleObject    = ObjectReference{APIVersion: batchv1.GroupVersion.String(), Kind: "Module"}
+	SecretObject    = ObjectReference{APIVersion: "v1", Kind: "Secret"}
+)
+
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
+// Entry is a single audit record of a mutating request.
+type Entry struct {
+	Timestamp  time.Time       `json:"timestamp"`
+	User       string          `json:"user"`
+	Groups     []string        `json:"groups,omitempty"`
+	RemoteAddr string          `json:"remoteAddr"`
+	Method     string          `json:"method"`
+	Route      string          `json:"route"`
+	Object     ObjectReference `json:"object"`
+	Body       any             `json:"body,omitempty"`
+	Status     int             `json:"status"`
+	Outcome    Outcome         `json:"outcome"`
+	// DryRun marks a request validated by the Kubernetes API without persisting its change.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// Sink persists audit entries somewhere outside the process.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, entry Entry) error
+}
+
+// queuedEntry is an entry waiting to be written to the sinks, with the context of its request.
+type queuedEntry struct {
+	ctx   context.Context
+	entry Entry
+}
+
+// queueSize is the number of entries waiting for the sinks before Record blocks.
+const queueSize = 1024
+
+// Auditor fans audit entries out to the configured sinks and keeps the most recent ones for querying.
+// Entries are written to the sinks by Run, outside of the request they record.
+type Auditor struct {
+	logger *zap.Logger
+	store  *store
+	sinks  []Sink
+	queue  chan queuedEntry
+	done   chan struct{}
+
+	// closed is set by Close, after which entries are written right away. mu guards it and the queue.
+	mu     sync.RWMutex
+	closed bool
+
+	// defaultNamespace is recorded for requests that do not name a namespace.
+	defaultNamespace string
+}
+
+func NewAuditor(logger *zap.Logger, bufferSize int, defaultNamespace string, sinks ...Sink) *Auditor {
+	return &Auditor{
+		logger:           logger,
+		store:            newStore(bufferSize),
+		sinks:            sinks,
+		queue:            make(chan queuedEntry, queueSize),
+		done:             make(chan struct{}),
+		defaultNamespace: defaultNamespace,
+	}
+}
+
+// Record keeps the entry for querying and queues it for the sinks.
+// It only waits for the sinks when they fell queueSize entries behind.
+func (a *Auditor) Record(ctx context.Context, entry Entry) {
+	a.store.add(entry)
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	// Requests outliving the shutdown grace period still get their entries written
+	if a.closed {
+		a.write(ctx, entry)
+		return
+	}
+
+	a.queue <- queuedEntry{ctx: ctx, entry: entry}
+}
+
+// Run writes queued entries to the sinks until the auditor is closed.
+func (a *Auditor) Run() {
+	defer close(a.done)
+
+	for queued := range a.queue {
+		a.write(queued.ctx, queued.entry)
+	}
+}
+
+// Close waits for Run to write the queued entries.
+func (a *Auditor) Close() {
+	a.mu.Lock()
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	<-a.done
+}
+
+func (a *Auditor) write(ctx context.Context, entry Entry) {
+	for _, sink := range a.sinks {
+		if err := sink.Write(ctx, entry); err != nil {
+			a.logger.Error("failed to write audit entry",
+				zap.String("sink", sink.Name()),
+				zap.String("route", entry.Route),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// Query returns the most recent entries matching the filter, newest first.
+func (a *Auditor) Query(filter Filter) []Entry {
+	return a.store.query(filter)
+}
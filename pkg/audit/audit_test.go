@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// recordingSink keeps the routes of the entries written to it.
+type recordingSink struct {
+	mu     sync.Mutex
+	routes []string
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Write(_ context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.routes = append(s.routes, entry.Route)
+	return nil
+}
+
+func TestAuditorWritesEveryEntry(t *testing.T) {
+	sink := &recordingSink{}
+	auditor := NewAuditor(zap.NewNop(), 10, "default", sink)
+	go auditor.Run()
+
+	auditor.Record(context.Background(), Entry{Route: "/first"})
+	auditor.Record(context.Background(), Entry{Route: "/second"})
+	auditor.Close()
+
+	// Recorded by a request that outlived the shutdown
+	auditor.Record(context.Background(), Entry{Route: "/late"})
+
+	want := []string{"/first", "/second", "/late"}
+	if len(sink.routes) != len(want) {
+		t.Fatalf("routes = %v, want %v", sink.routes, want)
+	}
+	for i := range want {
+		if sink.routes[i] != want[i] {
+			t.Errorf("routes = %v, want %v", sink.routes, want)
+		}
+	}
+
+	if got := auditor.Query(Filter{}); len(got) != 3 {
+		t.Errorf("queried %d entries, want 3", len(got))
+	}
+}
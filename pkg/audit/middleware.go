@@ -0,0 +1,286 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/api/identity"
+	"github.com/forkspacer/api-server/pkg/api/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.yaml.in/yaml/v3"
+)
+
+// maxRecordedBodySize caps how much of a request body is kept in an audit entry.
+const maxRecordedBodySize = 64 << 10
+
+const redacted = "[REDACTED]"
+
+const jsonPatchMediaType = "application/json-patch+json"
+
+// sensitiveKeys are matched case-insensitively as substrings of body keys.
+var sensitiveKeys = []string{"kubeconfig", "password", "token", "credential", "apikey", "privatekey"}
+
+// Middleware records every mutating request handled by next.
+// The target object kind comes from target, its name and namespace from the request body.
+func (a *Auditor) Middleware(target ObjectReference) func(http.Handler) http.Handler {
+	return a.middleware(target, "")
+}
+
+// PathMiddleware is Middleware for routes naming their object in the path, through the nameParam
+// and "namespace" URL parameters. Parameters missing from a route, e.g. the name on creation,
+// are taken from the request body instead.
+func (a *Auditor) PathMiddleware(target ObjectReference, nameParam string) func(http.Handler) http.Handler {
+	return a.middleware(target, nameParam)
+}
+
+func (a *Auditor) middleware(target ObjectReference, nameParam string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var rawBody []byte
+			if !isMultipart(r) && r.Body != nil {
+				rawBody, _ = io.ReadAll(io.LimitReader(r.Body, maxRecordedBodySize))
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(rawBody), r.Body), r.Body}
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			timestamp := time.Now()
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			body := recordedBody(r, rawBody)
+			object := target
+			object.Name, object.Namespace = objectFromBody(body)
+			if nameParam != "" {
+				if name := chi.URLParam(r, nameParam); name != "" {
+					object.Name = name
+				}
+				if namespace := chi.URLParam(r, "namespace"); namespace != "" {
+					object.Namespace = namespace
+				}
+			}
+			if object.Namespace == "" {
+				object.Namespace = a.defaultNamespace
+			}
+
+			a.Record(context.WithoutCancel(r.Context()), newEntry(r, timestamp, object, body, status))
+		})
+	}
+}
+
+// RecordObject records the change a request made to one of several objects, such as the manifests
+// of an apply, with the body and status of that object alone. The request itself is not recorded.
+func (a *Auditor) RecordObject(r *http.Request, object ObjectReference, body any, status int) {
+	if object.Namespace == "" {
+		object.Namespace = a.defaultNamespace
+	}
+
+	a.Record(context.WithoutCancel(r.Context()), newEntry(r, time.Now(), object, body, status))
+}
+
+func newEntry(r *http.Request, timestamp time.Time, object ObjectReference, body any, status int) Entry {
+	outcome := OutcomeSuccess
+	if status >= 400 {
+		outcome = OutcomeFailure
+	}
+
+	caller := identity.FromContext(r.Context())
+	return Entry{
+		Timestamp:  timestamp,
+		User:       caller.User,
+		Groups:     caller.Groups,
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		Route:      routePattern(r),
+		Object:     object,
+		Body:       redact(body),
+		Status:     status,
+		Outcome:    outcome,
+		DryRun:     isDryRun(r),
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func isMultipart(r *http.Request) bool {
+	contentType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+	return contentType == "multipart/form-data"
+}
+
+// isDryRun reports whether the request asked for a dry run. Invalid values fail the request anyway.
+func isDryRun(r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	return dryRun
+}
+
+func routePattern(r *http.Request) string {
+	if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil && routeCtx.RoutePattern() != "" {
+		return routeCtx.RoutePattern()
+	}
+
+	return r.URL.Path
+}
+
+// recordedBody decodes the request body into a generic value. Multipart requests are
+// recorded from their parsed form values; uploaded files are only noted by field name.
+func recordedBody(r *http.Request, rawBody []byte) any {
+	if isMultipart(r) {
+		if r.MultipartForm == nil {
+			return nil
+		}
+
+		form := map[string]any{}
+		for key, values := range r.MultipartForm.Value {
+			if len(values) == 1 {
+				form[key] = values[0]
+			} else {
+				form[key] = values
+			}
+		}
+		for key := range r.MultipartForm.File {
+			form[key] = redacted
+		}
+		return form
+	}
+
+	if len(rawBody) == 0 {
+		return nil
+	}
+
+	if contentType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";"); response.IsYAMLMediaType(contentType) {
+		// YAML bodies are recorded as their JSON counterpart, which has string keys only
+		var yamlBody any
+		if err := yaml.Unmarshal(rawBody, &yamlBody); err == nil {
+			rawBody, _ = json.Marshal(yamlBody)
+		}
+	}
+
+	var body any
+	if err := json.Unmarshal(rawBody, &body); err != nil {
+		// Undecodable bodies are recorded as a note rather than verbatim since they may hold secrets
+		return "undecodable request body"
+	}
+
+	if contentType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";"); contentType == jsonPatchMediaType {
+		return redactJSONPatch(body)
+	}
+	return body
+}
+
+func objectFromBody(body any) (name, namespace string) {
+	fields, ok := body.(map[string]any)
+	if !ok {
+		return "", ""
+	}
+
+	name, _ = fields["name"].(string)
+	namespace, _ = fields["namespace"].(string)
+
+	return name, namespace
+}
+
+// freeFormPaths are paths of body members whose content is chosen by users, such as Helm values and
+// module config. Credentials in them cannot be told by their keys, so they are redacted as a whole.
+// A path matches at any depth, so that it covers request bodies as well as the spec of manifests and
+// merge patches; "*" matches any array index.
+var freeFormPaths = [][]string{
+	{"values", "*", "raw"},
+	{"config"},
+}
+
+func redact(value any) any {
+	return redactAt(nil, value)
+}
+
+func redactAt(path []string, value any) any {
+	if isFreeForm(path) {
+		return redacted
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(typed))
+		for key, item := range typed {
+			if isSensitiveKey(key) {
+				result[key] = redacted
+				continue
+			}
+			result[key] = redactAt(append(slices.Clip(path), key), item)
+		}
+		return result
+	case []any:
+		result := make([]any, len(typed))
+		for i, item := range typed {
+			result[i] = redactAt(append(slices.Clip(path), "*"), item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+func isFreeForm(path []string) bool {
+	for _, freeFormPath := range freeFormPaths {
+		if len(path) < len(freeFormPath) {
+			continue
+		}
+
+		suffix := path[len(path)-len(freeFormPath):]
+		if slices.EqualFunc(suffix, freeFormPath, func(segment, pattern string) bool {
+			return pattern == "*" || segment == pattern
+		}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactJSONPatch redacts the values of JSON patch operations, which may be anything from a flag to Helm
+// values, so that only the operations and the paths they change are recorded.
+func redactJSONPatch(body any) any {
+	operations, ok := body.([]any)
+	if !ok {
+		return body
+	}
+
+	for _, operation := range operations {
+		if fields, ok := operation.(map[string]any); ok {
+			if _, ok := fields["value"]; ok {
+				fields["value"] = redacted
+			}
+		}
+	}
+	return operations
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitiveKey := range sensitiveKeys {
+		if strings.Contains(key, sensitiveKey) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecordedBodyRedaction(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "sensitive key",
+			contentType: "application/json",
+			body:        `{"name":"dev","kubeconfig":"apiVersion: v1"}`,
+			want:        `{"kubeconfig":"[REDACTED]","name":"dev"}`,
+		},
+		{
+			name:        "helm raw values",
+			contentType: "application/json",
+			body:        `{"helm":{"values":[{"raw":{"auth":{"pass":"s3cret"}}},{"configMap":{"name":"values"}}]}}`,
+			want:        `{"helm":{"values":[{"raw":"[REDACTED]"},{"configMap":{"name":"values"}}]}}`,
+		},
+		{
+			name:        "module config",
+			contentType: "application/json",
+			body:        `{"name":"redis","config":{"dbPass":"s3cret"}}`,
+			want:        `{"config":"[REDACTED]","name":"redis"}`,
+		},
+		{
+			name:        "merge patch of the spec",
+			contentType: "application/merge-patch+json",
+			body:        `{"spec":{"hibernated":true,"helm":{"values":[{"raw":{"pass":"s3cret"}}]}}}`,
+			want:        `{"spec":{"helm":{"values":[{"raw":"[REDACTED]"}]},"hibernated":true}}`,
+		},
+		{
+			name:        "JSON patch values",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"replace","path":"/spec/helm/values/0/raw/pass","value":"s3cret"},{"op":"remove","path":"/spec/config"}]`,     //nolint:lll
+			want:        `[{"op":"replace","path":"/spec/helm/values/0/raw/pass","value":"[REDACTED]"},{"op":"remove","path":"/spec/config"}]`, //nolint:lll
+		},
+		{
+			name:        "YAML body",
+			contentType: "application/yaml",
+			body:        "name: redis\nconfig:\n  dbPass: s3cret\n",
+			want:        `{"config":"[REDACTED]","name":"redis"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+
+			got, err := json.Marshal(redact(recordedBody(r, []byte(tt.body))))
+			if err != nil {
+				t.Fatalf("failed to encode recorded body: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("recorded body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FileSink appends audit entries to a file as JSON lines.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Name() string { return "file" }
+
+func (s *FileSink) Write(_ context.Context, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// ZapSink writes audit entries to a zap logger.
+type ZapSink struct {
+	logger *zap.Logger
+}
+
+func NewZapSink(logger *zap.Logger) *ZapSink {
+	return &ZapSink{logger: logger.Named("audit")}
+}
+
+func (s *ZapSink) Name() string { return "zap" }
+
+func (s *ZapSink) Write(_ context.Context, entry Entry) error {
+	s.logger.Info("audit",
+		zap.Time("timestamp", entry.Timestamp),
+		zap.String("user", entry.User),
+		zap.Strings("groups", entry.Groups),
+		zap.String("remoteAddr", entry.RemoteAddr),
+		zap.String("method", entry.Method),
+		zap.String("route", entry.Route),
+		zap.Any("object", entry.Object),
+		zap.Any("body", entry.Body),
+		zap.Int("status", entry.Status),
+		zap.String("outcome", string(entry.Outcome)),
+		zap.Bool("dryRun", entry.DryRun),
+	)
+
+	return nil
+}
+
+// EventSink records audit entries as Kubernetes Events on the affected object.
+type EventSink struct {
+	client client.Client
+}
+
+// NewEventSink writes Events through kubeClient, the client the services share.
+func NewEventSink(kubeClient client.Client) *EventSink {
+	return &EventSink{client: kubeClient}
+}
+
+func (s *EventSink) Name() string { return "events" }
+
+var methodToEventReason = map[string]string{
+	"POST":   "Created",
+	"PUT":    "Updated",
+	"PATCH":  "Updated",
+	"DELETE": "Deleted",
+}
+
+func (s *EventSink) Write(ctx context.Context, entry Entry) error {
+	// Events are namespaced alongside the object they describe, which a dry run leaves untouched
+	if entry.Object.Name == "" || entry.Object.Namespace == "" || entry.DryRun {
+		return nil
+	}
+
+	eventType := corev1.EventTypeNormal
+	reason := methodToEventReason[entry.Method]
+	if entry.Outcome == OutcomeFailure {
+		eventType = corev1.EventTypeWarning
+		reason += "Failed"
+	}
+
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", entry.Object.Name, entry.Timestamp.UnixNano()),
+			Namespace: entry.Object.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: entry.Object.APIVersion,
+			Kind:       entry.Object.Kind,
+			Name:       entry.Object.Name,
+			Namespace:  entry.Object.Namespace,
+		},
+		Reason: reason,
+		Message: fmt.Sprintf("%s %s by %s from %s: %d",
+			entry.Method, entry.Route, entry.User, entry.RemoteAddr, entry.Status,
+		),
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: "forkspacer-api-server"},
+		FirstTimestamp: metav1.NewTime(entry.Timestamp),
+		LastTimestamp:  metav1.NewTime(entry.Timestamp),
+		Count:          1,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return s.client.Create(ctx, event)
+}
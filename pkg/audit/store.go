@@ -0,0 +1,71 @@
+package audit
+
+import "sync"
+
+// Filter narrows down audit entries. Empty fields match everything.
+type Filter struct {
+	User      string
+	Kind      string
+	Name      string
+	Namespace string
+	Limit     int
+}
+
+func (f Filter) matches(entry Entry) bool {
+	return (f.User == "" || f.User == entry.User) &&
+		(f.Kind == "" || f.Kind == entry.Object.Kind) &&
+		(f.Name == "" || f.Name == entry.Object.Name) &&
+		(f.Namespace == "" || f.Namespace == entry.Object.Namespace)
+}
+
+// store is a fixed size ring buffer of the most recent audit entries.
+type store struct {
+	mu      sync.RWMutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+func newStore(size int) *store {
+	return &store{entries: make([]Entry, size)}
+}
+
+func (s *store) add(entry Entry) {
+	if len(s.entries) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[s.next] = entry
+	s.next = (s.next + 1) % len(s.entries)
+	if s.next == 0 {
+		s.full = true
+	}
+}
+
+func (s *store) query(filter Filter) []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := s.next
+	if s.full {
+		count = len(s.entries)
+	}
+
+	result := []Entry{}
+	for i := 1; i <= count; i++ {
+		entry := s.entries[(s.next-i+len(s.entries))%len(s.entries)]
+		if !filter.matches(entry) {
+			continue
+		}
+
+		result = append(result, entry)
+		if filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+	}
+
+	return result
+}
@@ -1,30 +1,192 @@
 package config
 
 import (
-	"github.com/forkspacer/api-server/pkg/utils"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/tracing"
 	"github.com/hashicorp/go-multierror"
+	"go.yaml.in/yaml/v3"
 )
 
+// APIConfig is read from an optional YAML file and overridden by environment variables.
+//
+// Each field is tagged with its file key (yaml), its environment variable (env) and its
+// validation rules (validate). Fields tagged redact:"true" are hidden when the config is displayed:
+// file paths, which reveal the layout of the host, and identity headers, which tell how to spoof a caller.
 type APIConfig struct {
-	Dev     bool
-	APIPort uint16
+	Dev bool `yaml:"dev" env:"DEV"`
+	// LogLevel is one of debug, info, warn or error. It defaults to debug in dev mode and info otherwise.
+	LogLevel string `yaml:"logLevel" env:"LOG_LEVEL" validate:"omitempty,oneof=debug info warn error"`
+	APIPort  uint16 `yaml:"apiPort" env:"API_PORT" validate:"gte=1"`
+	// BindAddresses lists the hosts or IPs the API listeners bind to. Empty binds all interfaces.
+	BindAddresses []string `yaml:"bindAddresses" env:"BIND_ADDRESSES" validate:"dive,ip|hostname_rfc1123"`
+	// UnixSocket additionally serves the API over plain HTTP on a Unix domain socket, e.g. for sidecars.
+	UnixSocket string `yaml:"unixSocket" env:"UNIX_SOCKET" redact:"true"`
+
+	// Server timeouts guarding against slow clients. Zero disables a timeout.
+	// The write timeout does not apply to streaming endpoints.
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" env:"READ_HEADER_TIMEOUT" validate:"gte=0"`
+	ReadTimeout       time.Duration `yaml:"readTimeout" env:"READ_TIMEOUT" validate:"gte=0"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout" env:"WRITE_TIMEOUT" validate:"gte=0"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout" env:"IDLE_TIMEOUT" validate:"gte=0"`
+	// ShutdownGracePeriod is how long in-flight requests may finish once shutdown starts.
+	ShutdownGracePeriod time.Duration `yaml:"shutdownGracePeriod" env:"SHUTDOWN_GRACE_PERIOD" validate:"gt=0"`
+
+	// AdminPort serves profiling, build info, log level control, the config and the audit log
+	// on a separate listener. Zero disables the admin listener.
+	AdminPort uint16 `yaml:"adminPort" env:"ADMIN_PORT" validate:"omitempty,nefield=APIPort"`
+	// AdminBindAddresses lists the hosts or IPs the admin listener binds to. It defaults to the loopback
+	// interface since the admin endpoints are not authenticated. Empty binds all interfaces.
+	AdminBindAddresses []string `yaml:"adminBindAddresses" env:"ADMIN_BIND_ADDRESSES" validate:"dive,ip|hostname_rfc1123"` //nolint:lll
+
+	// CORSAllowedOrigins lists origins allowed to call the API from a browser. Wildcards are supported.
+	CORSAllowedOrigins []string `yaml:"corsAllowedOrigins" env:"CORS_ALLOWED_ORIGINS" validate:"dive,required"`
+
+	// DefaultNamespace is used whenever a request does not specify a namespace.
+	DefaultNamespace string `yaml:"defaultNamespace" env:"DEFAULT_NAMESPACE" validate:"dns1123label"`
+	// AllowedNamespaces holds path.Match patterns of namespaces the API may touch.
+	// An empty list allows every namespace.
+	AllowedNamespaces []string `yaml:"allowedNamespaces" env:"ALLOWED_NAMESPACES"`
+
+	// StrictKubeconfig rejects uploaded kubeconfigs that use exec plugins, auth providers
+	// or local file references, and minifies them to their selected context.
+	StrictKubeconfig bool `yaml:"strictKubeconfig" env:"STRICT_KUBECONFIG"`
+
+	// IdentityUserHeader and IdentityGroupsHeader name the headers an authenticating proxy
+	// uses to pass the caller identity. They are ignored when empty.
+	IdentityUserHeader   string `yaml:"identityUserHeader" env:"IDENTITY_USER_HEADER" redact:"true"`
+	IdentityGroupsHeader string `yaml:"identityGroupsHeader" env:"IDENTITY_GROUPS_HEADER" redact:"true"`
+
+	// AuditSinks lists where audit entries are written: file, zap and/or events.
+	AuditSinks []string `yaml:"auditSinks" env:"AUDIT_SINKS" validate:"dive,oneof=file zap events"`
+	// AuditFile is the JSON-lines file used by the file audit sink.
+	AuditFile string `yaml:"auditFile" env:"AUDIT_FILE" redact:"true"`
+	// AuditBufferSize is the number of recent audit entries kept in memory for querying.
+	AuditBufferSize int `yaml:"auditBufferSize" env:"AUDIT_BUFFER_SIZE" validate:"gte=0"`
+
+	// Per-client token buckets for read (GET) and write requests. A zero rate disables limiting.
+	RateLimitReadRPS    float64 `yaml:"rateLimitReadRPS" env:"RATE_LIMIT_READ_RPS" validate:"gte=0"`
+	RateLimitReadBurst  int     `yaml:"rateLimitReadBurst" env:"RATE_LIMIT_READ_BURST" validate:"gte=0"`
+	RateLimitWriteRPS   float64 `yaml:"rateLimitWriteRPS" env:"RATE_LIMIT_WRITE_RPS" validate:"gte=0"`
+	RateLimitWriteBurst int     `yaml:"rateLimitWriteBurst" env:"RATE_LIMIT_WRITE_BURST" validate:"gte=0"`
+	// MaxInflightRequests caps concurrent requests hitting the Kubernetes API. Zero disables the cap.
+	MaxInflightRequests int `yaml:"maxInflightRequests" env:"MAX_INFLIGHT_REQUESTS" validate:"gte=0"`
+	// MaxBodyBytes caps request bodies. Kubeconfig uploads have their own limit of 10 MiB.
+	MaxBodyBytes int64 `yaml:"maxBodyBytes" env:"MAX_BODY_BYTES" validate:"gt=0"`
+	// RequestTimeout bounds requests hitting the Kubernetes API. Zero disables the deadline.
+	RequestTimeout time.Duration `yaml:"requestTimeout" env:"REQUEST_TIMEOUT" validate:"gte=0"`
+	// IdempotencyKeyTTL is how long responses to create requests with an Idempotency-Key are
+	// replayed. Zero disables idempotency keys.
+	IdempotencyKeyTTL time.Duration `yaml:"idempotencyKeyTTL" env:"IDEMPOTENCY_KEY_TTL" validate:"gte=0"`
+
+	// Backend is kubernetes, or memory to run against an in-memory store without a cluster.
+	Backend string `yaml:"backend" env:"BACKEND" validate:"oneof=kubernetes memory"`
+	// MemoryFixturesFile is a YAML file of Workspaces, Modules and Secrets loaded by the memory backend.
+	MemoryFixturesFile string `yaml:"memoryFixturesFile" env:"MEMORY_FIXTURES_FILE" redact:"true"`
+	// MemoryPhaseInterval is how often the memory backend advances workspace and module phases.
+	MemoryPhaseInterval time.Duration `yaml:"memoryPhaseInterval" env:"MEMORY_PHASE_INTERVAL" validate:"gt=0"`
+
+	// KubernetesQPS and KubernetesBurst bound the requests made to the Kubernetes API server.
+	// Reads are served from a cache, so these mostly limit writes.
+	KubernetesQPS   float64 `yaml:"kubernetesQPS" env:"KUBERNETES_QPS" validate:"gt=0"`
+	KubernetesBurst int     `yaml:"kubernetesBurst" env:"KUBERNETES_BURST" validate:"gt=0"`
+
+	// TLSCertFile and TLSKeyFile enable HTTPS serving. Both are reloaded from disk when they change.
+	TLSCertFile string `yaml:"tlsCertFile" env:"TLS_CERT_FILE" validate:"required_with=TLSKeyFile TLSClientCAFile" redact:"true"` //nolint:lll
+	TLSKeyFile  string `yaml:"tlsKeyFile" env:"TLS_KEY_FILE" validate:"required_with=TLSCertFile" redact:"true"`
+	// TLSClientCAFile enables client certificate verification. The verified certificate's
+	// common name and organizations become the caller identity.
+	TLSClientCAFile string `yaml:"tlsClientCAFile" env:"TLS_CLIENT_CA_FILE" redact:"true"`
+	// TLSRequireClientCert rejects clients without a verified certificate when a client CA is set.
+	TLSRequireClientCert bool `yaml:"tlsRequireClientCert" env:"TLS_REQUIRE_CLIENT_CERT"`
+	// TLSReloadInterval is how often the certificate files are checked for changes.
+	TLSReloadInterval time.Duration `yaml:"tlsReloadInterval" env:"TLS_RELOAD_INTERVAL" validate:"gt=0"`
+
+	// TracingExporter is one of none, otlp, stdout or file. The otlp exporter is configured
+	// through the standard OTEL_EXPORTER_OTLP_* environment variables.
+	TracingExporter string `yaml:"tracingExporter" env:"TRACING_EXPORTER" validate:"oneof=none otlp stdout file"`
+	// TracingFile receives JSON encoded spans when the file exporter is used.
+	TracingFile string `yaml:"tracingFile" env:"TRACING_FILE" validate:"required_if=TracingExporter file" redact:"true"`
+	// TracingSampleRatio is the fraction of new traces that are sampled.
+	TracingSampleRatio float64 `yaml:"tracingSampleRatio" env:"TRACING_SAMPLE_RATIO" validate:"gte=0,lte=1"`
 }
 
-func NewAPIConfig() (*APIConfig, *multierror.Error) {
+func defaultAPIConfig() APIConfig {
+	return APIConfig{
+		Dev:                  true,
+		APIPort:              8421,
+		ReadHeaderTimeout:    10 * time.Second,
+		ReadTimeout:          time.Minute,
+		WriteTimeout:         time.Minute,
+		IdleTimeout:          2 * time.Minute,
+		ShutdownGracePeriod:  20 * time.Second,
+		AdminPort:            8422,
+		AdminBindAddresses:   []string{"127.0.0.1"},
+		CORSAllowedOrigins:   []string{"https://*", "http://*"},
+		DefaultNamespace:     "default",
+		StrictKubeconfig:     true,
+		AuditSinks:           []string{"zap"},
+		AuditBufferSize:      1000,
+		RateLimitReadRPS:     20,
+		RateLimitReadBurst:   40,
+		RateLimitWriteRPS:    5,
+		RateLimitWriteBurst:  10,
+		MaxInflightRequests:  100,
+		MaxBodyBytes:         1 << 20,
+		RequestTimeout:       30 * time.Second,
+		IdempotencyKeyTTL:    24 * time.Hour,
+		Backend:              "kubernetes",
+		MemoryPhaseInterval:  5 * time.Second,
+		KubernetesQPS:        20,
+		KubernetesBurst:      30,
+		TLSRequireClientCert: true,
+		TLSReloadInterval:    10 * time.Second,
+		TracingExporter:      tracing.ExporterNone,
+		TracingSampleRatio:   1,
+	}
+}
+
+// NewAPIConfig builds the config from its defaults, the YAML file at configFile (if not empty)
+// and environment variables, in increasing order of precedence. All problems are reported together.
+func NewAPIConfig(configFile string) (*APIConfig, *multierror.Error) {
 	var errs *multierror.Error
 
-	dev, err := utils.GetEnvOr("DEV", true)
-	if err != nil && err != utils.ErrEnvNotFound {
-		errs = multierror.Append(err, errs)
+	apiConfig := defaultAPIConfig()
+
+	if configFile != "" {
+		if err := readConfigFile(configFile, &apiConfig); err != nil {
+			errs = multierror.Append(err, errs)
+		}
+	}
+
+	if err := applyEnv(&apiConfig); err != nil {
+		errs = multierror.Append(errs, err.Errors...)
+	}
+
+	if err := validate(&apiConfig); err != nil {
+		errs = multierror.Append(errs, err.Errors...)
+	}
+
+	return &apiConfig, errs
+}
+
+func readConfigFile(configFile string, apiConfig *APIConfig) error {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	apiPort, err := utils.GetEnvOr[uint16]("API_PORT", 8421)
-	if err != nil && err != utils.ErrEnvNotFound {
-		errs = multierror.Append(err, errs)
+	// Unknown keys are rejected so that typos do not silently fall back to defaults
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(apiConfig); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", configFile, err)
 	}
 
-	return &APIConfig{
-		Dev:     dev,
-		APIPort: apiPort,
-	}, errs
+	return nil
 }
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/utils"
+	"github.com/hashicorp/go-multierror"
+)
+
+// applyEnv overrides every field whose env variable is set.
+func applyEnv(apiConfig *APIConfig) *multierror.Error {
+	var errs *multierror.Error
+
+	value := reflect.ValueOf(apiConfig).Elem()
+	for i := range value.NumField() {
+		envName := value.Type().Field(i).Tag.Get("env")
+		if envName == "" {
+			continue
+		}
+
+		var err error
+		switch target := value.Field(i).Addr().Interface().(type) {
+		case *string:
+			err = envOverride(target, envName)
+		case *bool:
+			err = envOverride(target, envName)
+		case *int:
+			err = envOverride(target, envName)
+		case *int64:
+			err = envOverride(target, envName)
+		case *uint16:
+			err = envOverride(target, envName)
+		case *float64:
+			err = envOverride(target, envName)
+		case *time.Duration:
+			err = envOverride(target, envName)
+		case *[]string:
+			var values []string
+			values, err = utils.GetEnvListOr(envName, *target)
+			if err == nil {
+				*target = values
+			}
+		default:
+			err = fmt.Errorf("environment variable %s has an unsupported type %T", envName, target)
+		}
+
+		if err != nil && err != utils.ErrEnvNotFound {
+			errs = multierror.Append(err, errs)
+		}
+	}
+
+	return errs
+}
+
+func envOverride[T utils.ParseStringSupportTypes](target *T, envName string) error {
+	value, err := utils.GetEnv[T](envName)
+	if err != nil {
+		return err
+	}
+
+	*target = value
+	return nil
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"reflect"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Redacted returns the config keyed by its file keys, with fields tagged redact:"true" masked
+// whenever they are set.
+func (c *APIConfig) Redacted() (map[string]any, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+
+	value := reflect.ValueOf(c).Elem()
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+		if field.Tag.Get("redact") == "true" && !value.Field(i).IsZero() {
+			values[field.Tag.Get("yaml")] = redactedValue
+		}
+	}
+
+	return values, nil
+}
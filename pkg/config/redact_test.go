@@ -0,0 +1,78 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// sensitiveSettings are the file keys of the settings Redacted must never show.
+var sensitiveSettings = []string{
+	"unixSocket",
+	"identityUserHeader",
+	"identityGroupsHeader",
+	"auditFile",
+	"memoryFixturesFile",
+	"tlsCertFile",
+	"tlsKeyFile",
+	"tlsClientCAFile",
+	"tracingFile",
+}
+
+func TestSensitiveSettingsAreTagged(t *testing.T) {
+	fields := map[string]reflect.StructField{}
+	configType := reflect.TypeFor[APIConfig]()
+	for i := range configType.NumField() {
+		field := configType.Field(i)
+		fields[field.Tag.Get("yaml")] = field
+	}
+
+	for _, key := range sensitiveSettings {
+		field, ok := fields[key]
+		if !ok {
+			t.Errorf("setting %s does not exist", key)
+			continue
+		}
+		if field.Tag.Get("redact") != "true" {
+			t.Errorf("setting %s is not tagged redact:\"true\"", key)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	apiConfig := defaultAPIConfig()
+	value := reflect.ValueOf(&apiConfig).Elem()
+	for i := range value.NumField() {
+		if value.Type().Field(i).Tag.Get("redact") == "true" {
+			value.Field(i).SetString("secret")
+		}
+	}
+
+	values, err := apiConfig.Redacted()
+	if err != nil {
+		t.Fatalf("Redacted() error = %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		want any
+	}{
+		{key: "identityUserHeader", want: redactedValue},
+		{key: "tlsKeyFile", want: redactedValue},
+		{key: "tracingFile", want: redactedValue},
+		{key: "defaultNamespace", want: "default"},
+		{key: "apiPort", want: 8421},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := values[tt.key]; got != tt.want {
+				t.Errorf("Redacted()[%q] = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	// Unset settings are shown as they are, so that they are not mistaken for set ones
+	apiConfig.TLSKeyFile = ""
+	if values, _ := apiConfig.Redacted(); values["tlsKeyFile"] != "" {
+		t.Errorf("Redacted()[tlsKeyFile] = %v for an unset setting, want empty", values["tlsKeyFile"])
+	}
+}
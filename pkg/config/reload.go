@@ -0,0 +1,114 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// reloadInterval is how often the config file is checked for changes.
+const reloadInterval = 10 * time.Second
+
+// Store holds the effective config, which changes when the config file is reloaded.
+type Store struct {
+	current atomic.Pointer[APIConfig]
+}
+
+func NewStore(apiConfig *APIConfig) *Store {
+	store := &Store{}
+	store.current.Store(apiConfig)
+	return store
+}
+
+func (s *Store) Load() *APIConfig {
+	return s.current.Load()
+}
+
+// withReloadable returns a copy of c with the settings that are safe to change while
+// running taken from next.
+func (c *APIConfig) withReloadable(next *APIConfig) *APIConfig {
+	merged := *c
+	merged.LogLevel = next.LogLevel
+	merged.CORSAllowedOrigins = next.CORSAllowedOrigins
+	merged.RateLimitReadRPS = next.RateLimitReadRPS
+	merged.RateLimitReadBurst = next.RateLimitReadBurst
+	merged.RateLimitWriteRPS = next.RateLimitWriteRPS
+	merged.RateLimitWriteBurst = next.RateLimitWriteBurst
+	return &merged
+}
+
+// Watch polls configFile until ctx is done. When the file changes, the config is built again
+// and its reloadable settings are stored and passed to apply. An invalid file is reported and
+// the previous config kept; changes to other settings are reported and take effect on restart.
+func (s *Store) Watch(ctx context.Context, logger *zap.Logger, configFile string, apply func(*APIConfig)) {
+	stamp, _ := fileStamp(configFile)
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			currentStamp, err := fileStamp(configFile)
+			if err != nil {
+				logger.Error("failed to check config file", zap.Error(err))
+				continue
+			}
+			if currentStamp == stamp {
+				continue
+			}
+			stamp = currentStamp
+
+			next, errs := NewAPIConfig(configFile)
+			if errs != nil {
+				for _, err := range errs.Errors {
+					logger.Error("config error, keeping the previous config", zap.Error(err))
+				}
+				continue
+			}
+
+			reloaded := s.Load().withReloadable(next)
+			if restartRequired := changedSettings(reloaded, next); len(restartRequired) > 0 {
+				logger.Warn("config settings changed that require a restart",
+					zap.Strings("settings", restartRequired),
+				)
+			}
+
+			s.current.Store(reloaded)
+			apply(reloaded)
+
+			logger.Info("reloaded config file", zap.String("file", configFile))
+		}
+	}
+}
+
+// changedSettings names the settings that differ between reloaded and next.
+func changedSettings(reloaded, next *APIConfig) []string {
+	var changed []string
+
+	reloadedValue := reflect.ValueOf(reloaded).Elem()
+	nextValue := reflect.ValueOf(next).Elem()
+	for i := range reloadedValue.NumField() {
+		if !reflect.DeepEqual(reloadedValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			changed = append(changed, settingName(reloadedValue.Type().Field(i).Name))
+		}
+	}
+
+	return changed
+}
+
+func fileStamp(file string) (string, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", file, err)
+	}
+
+	return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size()), nil
+}
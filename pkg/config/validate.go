@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/forkspacer/api-server/pkg/api/validation"
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-multierror"
+)
+
+// validate checks apiConfig against its validate tags and the rules spanning several fields.
+func validate(apiConfig *APIConfig) *multierror.Error {
+	var errs *multierror.Error
+
+	if err := validation.Validate.Struct(apiConfig); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return multierror.Append(err, errs)
+		}
+
+		translator := validation.GetTranslation("en")
+		for _, fieldErr := range validationErrs {
+			errs = multierror.Append(
+				fmt.Errorf("invalid %s: %s", settingName(fieldErr.StructField()), fieldErr.Translate(translator)),
+				errs,
+			)
+		}
+	}
+
+	for _, pattern := range apiConfig.AllowedNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = multierror.Append(fmt.Errorf("invalid allowedNamespaces pattern %q: %w", pattern, err), errs)
+		}
+	}
+
+	if apiConfig.AuditFile == "" && slices.Contains(apiConfig.AuditSinks, "file") {
+		errs = multierror.Append(fmt.Errorf("auditFile is required when the file audit sink is enabled"), errs)
+	}
+
+	if apiConfig.Backend == "memory" && slices.Contains(apiConfig.AuditSinks, "events") {
+		errs = multierror.Append(fmt.Errorf("the events audit sink is not available with the memory backend"), errs)
+	}
+
+	return errs
+}
+
+// settingName names a field by its file key and environment variable, e.g. "apiPort (API_PORT)".
+// List element names such as "AuditSinks[0]" resolve to their list field.
+func settingName(fieldName string) string {
+	fieldName, _, _ = strings.Cut(fieldName, "[")
+
+	field, ok := reflect.TypeFor[APIConfig]().FieldByName(fieldName)
+	if !ok {
+		return fieldName
+	}
+
+	return fmt.Sprintf("%s (%s)", field.Tag.Get("yaml"), field.Tag.Get("env"))
+}
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+)
+
+// Check is a named dependency probe. It reports a problem by returning an error.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+type Status struct {
+	Healthy bool `json:"healthy"`
+	// Checks lists failed checks, or every check when verbose output was requested.
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+// Run executes all checks concurrently, each bounded by timeout.
+// Results are returned in the order the checks were given.
+func Run(ctx context.Context, timeout time.Duration, checks ...Check) []CheckResult {
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Go(func() {
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			results[i] = CheckResult{Name: check.Name, Healthy: true}
+			if err := check.Check(checkCtx); err != nil {
+				results[i].Healthy = false
+				results[i].Error = err.Error()
+			}
+		})
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Handler serves the combined result of checks: 200 when all pass, 503 otherwise.
+// The verbose query parameter includes passing checks in the response.
+func Handler(timeout time.Duration, checks ...Check) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		verbose := r.URL.Query().Has("verbose")
+
+		status := Status{Healthy: true}
+		for _, result := range Run(r.Context(), timeout, checks...) {
+			if !result.Healthy {
+				status.Healthy = false
+			}
+			if verbose || !result.Healthy {
+				status.Checks = append(status.Checks, result)
+			}
+		}
+
+		if !status.Healthy {
+			response.JSONServiceUnavailable(w, status)
+			return
+		}
+
+		response.JSONSuccess(w, 200, response.NewJSONSuccess(response.SuccessCodes.Ok, status))
+	}
+}
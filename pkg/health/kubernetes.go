@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// KubernetesAPICheck verifies that the Kubernetes API server answers version requests.
+func KubernetesAPICheck(client discovery.DiscoveryInterface) Check {
+	return Check{
+		Name: "kubernetes-api",
+		Check: func(ctx context.Context) error {
+			if err := client.RESTClient().Get().AbsPath("/version").Do(ctx).Error(); err != nil {
+				return fmt.Errorf("kubernetes API is unreachable: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// ResourcesServedCheck verifies that groupVersion is served and includes every resource.
+// It fails while the CRDs backing the resources are missing or not yet established.
+func ResourcesServedCheck(
+	client discovery.DiscoveryInterface,
+	groupVersion schema.GroupVersion,
+	resources ...string,
+) Check {
+	return Check{
+		Name: groupVersion.String(),
+		Check: func(ctx context.Context) error {
+			rawResourceList, err := client.RESTClient().Get().
+				AbsPath("/apis", groupVersion.Group, groupVersion.Version).
+				Do(ctx).Raw()
+			if err != nil {
+				return fmt.Errorf("%s is not served: %w", groupVersion, err)
+			}
+
+			resourceList := &metav1.APIResourceList{}
+			if err := json.Unmarshal(rawResourceList, resourceList); err != nil {
+				return fmt.Errorf("failed to decode %s resources: %w", groupVersion, err)
+			}
+
+			var missing []string
+			for _, resource := range resources {
+				if !slices.ContainsFunc(resourceList.APIResources, func(apiResource metav1.APIResource) bool {
+					return apiResource.Name == resource
+				}) {
+					missing = append(missing, resource)
+				}
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("%s does not serve %s", groupVersion, strings.Join(missing, ", "))
+			}
+
+			return nil
+		},
+	}
+}
+
+// CacheSyncedCheck verifies that an informer cache finished its initial sync.
+// waitForSync should return false once ctx is done, as the controller-runtime cache does.
+func CacheSyncedCheck(name string, waitForSync func(ctx context.Context) bool) Check {
+	return Check{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			if !waitForSync(ctx) {
+				return fmt.Errorf("%s has not synced", name)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// instrumentedClient records latency and errors of the calls services make through it.
+type instrumentedClient struct {
+	client.Client
+	service string
+}
+
+// InstrumentClient wraps c so that its reads and writes are recorded under the given service label.
+func InstrumentClient(service string, c client.Client) client.Client {
+	return instrumentedClient{Client: c, service: service}
+}
+
+func (c instrumentedClient) observe(operation string, obj runtime.Object, start time.Time, err error) {
+	kind := "unknown"
+	if obj != nil {
+		if gvk, gvkErr := apiutil.GVKForObject(obj, c.Scheme()); gvkErr == nil {
+			kind = strings.TrimSuffix(gvk.Kind, "List")
+		}
+	}
+
+	kubernetesRequestDuration.WithLabelValues(c.service, operation, kind).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		reason := string(apierrors.ReasonForError(err))
+		if reason == "" {
+			reason = "Unknown"
+		}
+		kubernetesRequestErrorsTotal.WithLabelValues(c.service, operation, kind, reason).Inc()
+	}
+}
+
+func (c instrumentedClient) Get(
+	ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption,
+) error {
+	start := time.Now()
+	err := c.Client.Get(ctx, key, obj, opts...)
+	c.observe("get", obj, start, err)
+	return err
+}
+
+func (c instrumentedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	start := time.Now()
+	err := c.Client.List(ctx, list, opts...)
+	c.observe("list", list, start, err)
+	return err
+}
+
+func (c instrumentedClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	start := time.Now()
+	err := c.Client.Create(ctx, obj, opts...)
+	c.observe("create", obj, start, err)
+	return err
+}
+
+func (c instrumentedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	start := time.Now()
+	err := c.Client.Update(ctx, obj, opts...)
+	c.observe("update", obj, start, err)
+	return err
+}
+
+func (c instrumentedClient) Patch(
+	ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption,
+) error {
+	start := time.Now()
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	c.observe("patch", obj, start, err)
+	return err
+}
+
+func (c instrumentedClient) Apply(
+	ctx context.Context, obj runtime.ApplyConfiguration, opts ...client.ApplyOption,
+) error {
+	start := time.Now()
+	err := c.Client.Apply(ctx, obj, opts...)
+	// Apply configurations built from unstructured objects are objects, typed ones have no kind to report
+	object, _ := obj.(runtime.Object)
+	c.observe("apply", object, start, err)
+	return err
+}
+
+func (c instrumentedClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	start := time.Now()
+	err := c.Client.Delete(ctx, obj, opts...)
+	c.observe("delete", obj, start, err)
+	return err
+}
+
+func (c instrumentedClient) DeleteAllOf(
+	ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption,
+) error {
+	start := time.Now()
+	err := c.Client.DeleteAllOf(ctx, obj, opts...)
+	c.observe("deleteallof", obj, start, err)
+	return err
+}
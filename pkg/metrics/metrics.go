@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "forkspacer_api"
+
+// Registry holds every metric exposed on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route pattern, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route pattern, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	kubernetesRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kubernetes_request_duration_seconds",
+		Help:      "Kubernetes client call latency by service, operation and kind.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "operation", "kind"})
+
+	kubernetesRequestErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kubernetes_request_errors_total",
+		Help:      "Failed Kubernetes client calls by service, operation, kind and status reason.",
+	}, []string{"service", "operation", "kind", "reason"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestsTotal,
+		httpRequestDuration,
+		kubernetesRequestDuration,
+		kubernetesRequestErrorsTotal,
+	)
+}
+
+// Handler serves Registry in the Prometheus exposition format.
+// A failing collector drops only its own metrics instead of the whole scrape.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{
+		Registry:      Registry,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
@@ -0,0 +1,84 @@
+package forkspacer
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ApplyFieldManager owns the fields of the manifests applied through the API.
+const ApplyFieldManager = "forkspacer-api-server-apply"
+
+// ApplyResult tells what applying a manifest did to its object.
+type ApplyResult string
+
+const (
+	ApplyCreated    ApplyResult = "created"
+	ApplyConfigured ApplyResult = "configured"
+	ApplyUnchanged  ApplyResult = "unchanged"
+)
+
+// applyObject applies a manifest with server-side apply as ApplyFieldManager, and returns the applied object.
+// The manifest must be a T in a resolved namespace. Fields owned by other managers conflict unless
+// client.ForceOwnership is among opts.
+func applyObject[T any, PT interface {
+	*T
+	client.Object
+}](
+	ctx context.Context, kubeClient client.Client, manifest *unstructured.Unstructured, opts ...client.ApplyOption,
+) (PT, ApplyResult, error) {
+	existing := PT(new(T))
+	if err := kubeClient.Get(ctx, client.ObjectKeyFromObject(manifest), existing); apierrors.IsNotFound(err) {
+		existing = nil
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	opts = append([]client.ApplyOption{client.FieldOwner(ApplyFieldManager)}, opts...)
+	if err := kubeClient.Apply(ctx, client.ApplyConfigurationFromUnstructured(manifest), opts...); err != nil {
+		return nil, "", err
+	}
+
+	applied := PT(new(T))
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(manifest.Object, applied); err != nil {
+		return nil, "", err
+	}
+
+	switch {
+	case existing == nil:
+		return applied, ApplyCreated, nil
+	case sameObject(existing, applied):
+		return applied, ApplyUnchanged, nil
+	default:
+		return applied, ApplyConfigured, nil
+	}
+}
+
+// sameObject reports whether an apply left an object as it was. Dry runs do not bump the resourceVersion,
+// so objects are compared without the metadata every write changes.
+func sameObject(before, after client.Object) bool {
+	before, after = before.DeepCopyObject().(client.Object), after.DeepCopyObject().(client.Object)
+	for _, obj := range []client.Object{before, after} {
+		obj.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+		obj.SetResourceVersion("")
+		obj.SetGeneration(0)
+		obj.SetManagedFields(nil)
+	}
+
+	return equality.Semantic.DeepEqual(before, after)
+}
+
+// manifestNamespace returns the namespace of a manifest, nil when it has none.
+func manifestNamespace(manifest *unstructured.Unstructured) *string {
+	if manifest.GetNamespace() == "" {
+		return nil
+	}
+
+	namespace := manifest.GetNamespace()
+	return &namespace
+}
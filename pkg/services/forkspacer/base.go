@@ -1,5 +1,13 @@
 package forkspacer
 
+import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
 const (
 	BaseLabel = "forkspacer"
 )
@@ -14,3 +22,24 @@ type ResourceReference struct {
 	Name      string
 	Namespace string
 }
+
+// nameAttribute labels service spans with the name of the object they act on.
+func nameAttribute(name string) attribute.KeyValue {
+	return attribute.String("forkspacer.name", name)
+}
+
+// ErrPreconditionFailed is returned when an object is not at the resourceVersion a write is conditional on.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// checkResourceVersion returns ErrPreconditionFailed when obj is not at the expected resourceVersion.
+// It is no conflict, so that the write fails at once instead of being retried.
+// A nil resourceVersion expects nothing.
+func checkResourceVersion(obj client.Object, resource string, resourceVersion *string) error {
+	if resourceVersion == nil || obj.GetResourceVersion() == *resourceVersion {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s %q is at resourceVersion %s, not %s",
+		ErrPreconditionFailed, resource, obj.GetName(), obj.GetResourceVersion(), *resourceVersion,
+	)
+}
@@ -0,0 +1,155 @@
+package forkspacer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/forkspacer/api-server/pkg/tracing"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// informerSetupInterval is how often informer setup is retried while the CRDs are not served.
+const informerSetupInterval = 5 * time.Second
+
+// ModuleWorkspaceIndex indexes modules by the "namespace/name" of the workspace they belong to.
+const ModuleWorkspaceIndex = "spec.workspace"
+
+type ClientOptions struct {
+	// QPS and Burst bound the requests made to the Kubernetes API server.
+	QPS   float32
+	Burst int
+
+	// Namespaces limits the cache to the given namespaces. An empty list caches every namespace.
+	Namespaces []string
+}
+
+// Client is the Kubernetes client shared by the services. Workspaces, Modules and kubeconfig
+// Secrets are read from an informer cache, while writes go straight to the API server.
+type Client struct {
+	client.Client
+	start       func(ctx context.Context) error
+	waitForSync func(ctx context.Context) bool
+}
+
+func NewScheme() (*runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add go client to schemes: %w", err)
+	}
+	if err := batchv1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add batch.forkspacer.com/v1 to scheme: %w", err)
+	}
+
+	return scheme, nil
+}
+
+func NewClient(restConfig *rest.Config, options ClientOptions) (*Client, error) {
+	restConfig = rest.CopyConfig(restConfig)
+	restConfig.QPS = options.QPS
+	restConfig.Burst = options.Burst
+	restConfig.Wrap(tracing.WrapTransport)
+
+	scheme, err := NewScheme()
+	if err != nil {
+		return nil, err
+	}
+
+	cacheOptions := cache.Options{
+		Scheme: scheme,
+		ByObject: map[client.Object]cache.ByObject{
+			// Only kubeconfig secrets are cached, other secrets are never read
+			&corev1.Secret{}: {
+				Label: labels.SelectorFromSet(labels.Set{BaseLabel: Labels.WorkspaceKubeconfigSecret}),
+			},
+		},
+		// Reads of other types fail instead of silently starting cluster-wide informers
+		ReaderFailOnMissingInformer: true,
+	}
+	if len(options.Namespaces) > 0 {
+		cacheOptions.DefaultNamespaces = map[string]cache.Config{}
+		for _, namespace := range options.Namespaces {
+			cacheOptions.DefaultNamespaces[namespace] = cache.Config{}
+		}
+	}
+
+	informerCache, err := cache.New(restConfig, cacheOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes cache: %w", err)
+	}
+
+	ctrlClient, err := client.New(restConfig, client.Options{
+		Scheme: scheme,
+		Cache:  &client.CacheOptions{Reader: informerCache},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create controller-runtime client: %w", err)
+	}
+
+	return &Client{
+		Client: ctrlClient,
+		// Informers are set up once the Forkspacer CRDs are served,
+		// so that the server can start before they are installed
+		start: func(ctx context.Context) error {
+			var setupErr error
+			if err := wait.PollUntilContextCancel(ctx, informerSetupInterval, true,
+				func(ctx context.Context) (bool, error) {
+					setupErr = setupInformers(ctx, informerCache)
+					return setupErr == nil, nil
+				},
+			); err != nil {
+				return fmt.Errorf("failed to set up informers: %w", errors.Join(err, setupErr))
+			}
+
+			return informerCache.Start(ctx)
+		},
+		waitForSync: informerCache.WaitForCacheSync,
+	}, nil
+}
+
+func setupInformers(ctx context.Context, informerCache cache.Cache) error {
+	for _, obj := range []client.Object{&batchv1.Workspace{}, &batchv1.Module{}, &corev1.Secret{}} {
+		if _, err := informerCache.GetInformer(ctx, obj); err != nil {
+			return fmt.Errorf("failed to create %T informer: %w", obj, err)
+		}
+	}
+
+	if err := informerCache.IndexField(ctx, &batchv1.Module{}, ModuleWorkspaceIndex, indexModuleWorkspace); err != nil {
+		return fmt.Errorf("failed to index modules by workspace: %w", err)
+	}
+
+	return nil
+}
+
+// Start runs the client's background work, such as the informers, until ctx is done.
+func (c *Client) Start(ctx context.Context) error {
+	return c.start(ctx)
+}
+
+// WaitForCacheSync blocks until the initial listing of every cached type completed.
+// It returns false if ctx is done first.
+func (c *Client) WaitForCacheSync(ctx context.Context) bool {
+	return c.waitForSync(ctx)
+}
+
+func indexModuleWorkspace(obj client.Object) []string {
+	module, ok := obj.(*batchv1.Module)
+	if !ok {
+		return nil
+	}
+
+	return []string{objectKey(module.Spec.Workspace.Namespace, module.Spec.Workspace.Name)}
+}
+
+func objectKey(namespace, name string) string {
+	return namespace + "/" + name
+}
@@ -0,0 +1,105 @@
+package forkspacer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// collectTimeout bounds the list calls made for a single scrape.
+const collectTimeout = 10 * time.Second
+
+var (
+	workspacesDesc = prometheus.NewDesc(
+		"forkspacer_api_workspaces",
+		"Number of workspaces by namespace, phase, hibernation state and managed cluster backend.",
+		[]string{"namespace", "phase", "hibernated", "backend"}, nil,
+	)
+	modulesDesc = prometheus.NewDesc(
+		"forkspacer_api_modules",
+		"Number of modules by namespace, phase and hibernation state.",
+		[]string{"namespace", "phase", "hibernated"}, nil,
+	)
+)
+
+// ResourceCollector reports workspace and module counts at scrape time from the shared client's cache.
+// Only namespaces allowed by the policy are counted.
+type ResourceCollector struct {
+	client     client.Client
+	namespaces NamespacePolicy
+}
+
+func NewResourceCollector(kubeClient client.Client, namespaces NamespacePolicy) *ResourceCollector {
+	return &ResourceCollector{client: kubeClient, namespaces: namespaces}
+}
+
+func (c *ResourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workspacesDesc
+	ch <- modulesDesc
+}
+
+func (c *ResourceCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	workspaces := &batchv1.WorkspaceList{}
+	if err := c.client.List(ctx, workspaces); err != nil {
+		ch <- prometheus.NewInvalidMetric(workspacesDesc, fmt.Errorf("failed to list workspaces: %w", err))
+	} else {
+		counts := map[[4]string]int{}
+		for _, workspace := range workspaces.Items {
+			if c.namespaces.Check(workspace.Namespace) != nil {
+				continue
+			}
+
+			backend := "none"
+			if workspace.Spec.ManagedCluster != nil && workspace.Spec.ManagedCluster.Backend != "" {
+				backend = string(workspace.Spec.ManagedCluster.Backend)
+			}
+			counts[[4]string{
+				workspace.Namespace,
+				phaseLabel(string(workspace.Status.Phase)),
+				strconv.FormatBool(workspace.Spec.Hibernated),
+				backend,
+			}]++
+		}
+
+		for labels, count := range counts {
+			ch <- prometheus.MustNewConstMetric(workspacesDesc, prometheus.GaugeValue, float64(count), labels[:]...)
+		}
+	}
+
+	modules := &batchv1.ModuleList{}
+	if err := c.client.List(ctx, modules); err != nil {
+		ch <- prometheus.NewInvalidMetric(modulesDesc, fmt.Errorf("failed to list modules: %w", err))
+	} else {
+		counts := map[[3]string]int{}
+		for _, module := range modules.Items {
+			if c.namespaces.Check(module.Namespace) != nil {
+				continue
+			}
+
+			counts[[3]string{
+				module.Namespace,
+				phaseLabel(string(module.Status.Phase)),
+				strconv.FormatBool(module.Spec.Hibernated),
+			}]++
+		}
+
+		for labels, count := range counts {
+			ch <- prometheus.MustNewConstMetric(modulesDesc, prometheus.GaugeValue, float64(count), labels[:]...)
+		}
+	}
+}
+
+func phaseLabel(phase string) string {
+	if phase == "" {
+		return "unknown"
+	}
+	return phase
+}
@@ -0,0 +1,151 @@
+// Package forkspacertest provides fixtures for testing the API handlers against the in-memory backend.
+package forkspacertest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+	"github.com/forkspacer/api-server/pkg/services/forkspacer"
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// Namespaces allows the default namespace and team-* namespaces, so that any other namespace is forbidden.
+var Namespaces = forkspacer.NamespacePolicy{Default: "default", Allowed: []string{"default", "team-*"}}
+
+// Objects are a workspace with a module in its namespace and one in another namespace,
+// and a kubeconfig secret next to a secret the API server did not create.
+// Each is at resourceVersion 1.
+func Objects() []client.Object {
+	return []client.Object{
+		&batchv1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{Name: "dev", Namespace: "default"},
+			Spec: batchv1.WorkspaceSpec{
+				Type:       batchv1.WorkspaceTypeKubernetes,
+				Connection: batchv1.WorkspaceConnection{Type: "in-cluster"},
+			},
+		},
+		Module("default", "redis"),
+		Module("team-a", "cache"),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dev-kubeconfig",
+				Namespace: "default",
+				Labels:    map[string]string{forkspacer.BaseLabel: forkspacer.Labels.WorkspaceKubeconfigSecret},
+			},
+		},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry-token", Namespace: "default"}},
+	}
+}
+
+// Module returns a custom module of the dev workspace.
+func Module(namespace, name string) *batchv1.Module {
+	return &batchv1.Module{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: batchv1.ModuleSpec{
+			Workspace: batchv1.ModuleWorkspaceReference{Name: "dev", Namespace: "default"},
+			Custom:    &batchv1.ModuleSpecCustom{Image: "redis:8"},
+		},
+	}
+}
+
+// NewClient returns an in-memory backend holding objects, whose calls go through funcs.
+func NewClient(t *testing.T, funcs interceptor.Funcs, objects ...client.Object) client.Client {
+	t.Helper()
+
+	memoryClient, err := forkspacer.NewMemoryClient(forkspacer.MemoryOptions{DefaultNamespace: "default"})
+	if err != nil {
+		t.Fatalf("failed to create memory client: %v", err)
+	}
+
+	for _, object := range objects {
+		if err := memoryClient.Create(context.Background(), object.DeepCopyObject().(client.Object)); err != nil {
+			t.Fatalf("failed to create %s: %v", object.GetName(), err)
+		}
+	}
+
+	return interceptor.NewClient(memoryClient.Client.(client.WithWatch), funcs)
+}
+
+// NewRequest returns a request with body, sent as JSON unless it is empty.
+func NewRequest(method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+
+	return r
+}
+
+// Serve serves r with handler and fails the test unless the response has wantStatus and wantCode,
+// the code of the success or error in its body, empty when there is no body.
+// It returns the header and the data of the response.
+func Serve(t *testing.T, handler http.Handler, r *http.Request, wantStatus int, wantCode string) (http.Header, any) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != wantStatus {
+		t.Fatalf("status = %d, want %d: %s", w.Code, wantStatus, w.Body)
+	}
+
+	// Deletions and unmodified objects respond without a body
+	var resp response.Response
+	if w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response body %q: %v", w.Body, err)
+		}
+	}
+
+	var code string
+	var data any
+	switch {
+	case resp.Error != nil:
+		code, data = string(resp.Error.Code), resp.Error.Data
+	case resp.Success != nil:
+		code, data = string(resp.Success.Code), resp.Success.Data
+	}
+	if code != wantCode {
+		t.Fatalf("code = %q, want %q: %s", code, wantCode, w.Body)
+	}
+
+	return w.Header(), data
+}
+
+// GetObject reads the stored object named name in namespace into obj, and reports whether there is one.
+func GetObject(t *testing.T, kubeClient client.Client, obj client.Object, namespace, name string) bool {
+	t.Helper()
+
+	err := kubeClient.Get(context.Background(), client.ObjectKey{Name: name, Namespace: namespace}, obj)
+	if apierrors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("failed to get %s/%s: %v", namespace, name, err)
+	}
+
+	return true
+}
+
+// Member returns the member of a JSON object at path, or nil when it is missing.
+func Member(data any, path ...string) any {
+	for _, key := range path {
+		object, ok := data.(map[string]any)
+		if !ok {
+			return nil
+		}
+		data = object[key]
+	}
+
+	return data
+}
@@ -0,0 +1,211 @@
+package forkspacer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	batchv1 "github.com/forkspacer/forkspacer/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// Phases the in-memory backend moves workspaces and modules through.
+const (
+	memoryPhaseReady       = "ready"
+	memoryPhaseHibernating = "hibernating"
+	memoryPhaseHibernated  = "hibernated"
+	memoryPhaseResuming    = "resuming"
+)
+
+type MemoryOptions struct {
+	// FixturesFile is an optional multi-document YAML file of Workspaces, Modules and Secrets
+	// loaded at start. Objects without a namespace are put in DefaultNamespace.
+	FixturesFile     string
+	DefaultNamespace string
+
+	// TransitionInterval is how often phases advance one step towards the spec,
+	// e.g. from ready to hibernating and then to hibernated.
+	TransitionInterval time.Duration
+}
+
+// NewMemoryClient returns a client backed by an in-memory store instead of a cluster.
+// A background loop stands in for the operator by advancing phases when Start runs.
+func NewMemoryClient(options MemoryOptions) (*Client, error) {
+	scheme, err := NewScheme()
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures []client.Object
+	if options.FixturesFile != "" {
+		fixtures, err = readFixtures(scheme, options.FixturesFile, options.DefaultNamespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	memoryClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(fixtures...).
+		WithStatusSubresource(&batchv1.Workspace{}, &batchv1.Module{}).
+		WithIndex(&batchv1.Module{}, ModuleWorkspaceIndex, indexModuleWorkspace).
+		WithInterceptorFuncs(interceptor.Funcs{Create: setCreationTimestamp, Apply: applyInMemory}).
+		Build()
+
+	return &Client{
+		Client: memoryClient,
+		start: func(ctx context.Context) error {
+			advancePhases(ctx, memoryClient, options.TransitionInterval)
+			return nil
+		},
+		waitForSync: func(context.Context) bool { return true },
+	}, nil
+}
+
+func readFixtures(scheme *runtime.Scheme, fixturesFile, defaultNamespace string) ([]client.Object, error) {
+	file, err := os.Open(fixturesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixtures file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(file))
+
+	var fixtures []client.Object
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return fixtures, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixtures file %s: %w", fixturesFile, err)
+		}
+		if len(bytes.TrimSpace(document)) == 0 {
+			continue
+		}
+
+		obj, _, err := decoder.Decode(document, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode fixture in %s: %w", fixturesFile, err)
+		}
+
+		fixture, ok := obj.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("fixture %T in %s is not a Kubernetes object", obj, fixturesFile)
+		}
+		switch fixture.(type) {
+		case *batchv1.Workspace, *batchv1.Module, *corev1.Secret:
+		default:
+			return nil, fmt.Errorf("fixture %T in %s is not a Workspace, Module or Secret", obj, fixturesFile)
+		}
+
+		if fixture.GetNamespace() == "" {
+			fixture.SetNamespace(defaultNamespace)
+		}
+		if creationTimestamp := fixture.GetCreationTimestamp(); creationTimestamp.IsZero() {
+			fixture.SetCreationTimestamp(metav1.Now())
+		}
+		fixtures = append(fixtures, fixture)
+	}
+}
+
+// setCreationTimestamp sets the creation timestamp of new objects, as the API server does.
+func setCreationTimestamp(
+	ctx context.Context, memoryClient client.WithWatch, obj client.Object, opts ...client.CreateOption,
+) error {
+	obj.SetCreationTimestamp(metav1.Now())
+	return memoryClient.Create(ctx, obj, opts...)
+}
+
+// applyInMemory makes server-side applies behave as they do on the API server: new objects get
+// a creation timestamp, and dry runs, which the in-memory store would persist, are not.
+func applyInMemory(
+	ctx context.Context, memoryClient client.WithWatch, obj runtime.ApplyConfiguration, opts ...client.ApplyOption,
+) error {
+	applyOptions := (&client.ApplyOptions{}).ApplyOptions(opts)
+	if slices.Contains(applyOptions.DryRun, metav1.DryRunAll) {
+		return nil
+	}
+
+	// Apply configurations built from unstructured objects carry their metadata
+	if object, ok := obj.(client.Object); ok {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(object.GetObjectKind().GroupVersionKind())
+		if err := memoryClient.Get(ctx, client.ObjectKeyFromObject(object), existing); apierrors.IsNotFound(err) {
+			object.SetCreationTimestamp(metav1.Now())
+		}
+	}
+
+	return memoryClient.Apply(ctx, obj, opts...)
+}
+
+// advancePhases moves every workspace and module one phase closer to its spec per interval
+// until ctx is done. Failed updates, e.g. conflicts with a concurrent request, are retried on the next tick.
+func advancePhases(ctx context.Context, memoryClient client.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			workspaces := &batchv1.WorkspaceList{}
+			if err := memoryClient.List(ctx, workspaces); err == nil {
+				for i := range workspaces.Items {
+					workspace := &workspaces.Items[i]
+					phase, changed := nextPhase(string(workspace.Status.Phase), workspace.Spec.Hibernated)
+					if changed {
+						workspace.Status.Phase = batchv1.WorkspacePhase(phase)
+						_ = memoryClient.Status().Update(ctx, workspace)
+					}
+				}
+			}
+
+			modules := &batchv1.ModuleList{}
+			if err := memoryClient.List(ctx, modules); err == nil {
+				for i := range modules.Items {
+					module := &modules.Items[i]
+					phase, changed := nextPhase(string(module.Status.Phase), module.Spec.Hibernated)
+					if changed {
+						module.Status.Phase = batchv1.ModulePhase(phase)
+						_ = memoryClient.Status().Update(ctx, module)
+					}
+				}
+			}
+		}
+	}
+}
+
+// nextPhase returns the phase following phase for an object whose spec asks for hibernated.
+// Hibernating and resuming pass through an intermediate phase; new objects become ready directly.
+func nextPhase(phase string, hibernated bool) (string, bool) {
+	switch {
+	case hibernated && phase == memoryPhaseHibernating:
+		return memoryPhaseHibernated, true
+	case hibernated && phase != memoryPhaseHibernated:
+		return memoryPhaseHibernating, true
+	case !hibernated && phase == memoryPhaseHibernated:
+		return memoryPhaseResuming, true
+	case !hibernated && phase != memoryPhaseReady:
+		return memoryPhaseReady, true
+	default:
+		return phase, false
+	}
+}
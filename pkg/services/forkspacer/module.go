@@ -4,40 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 
-	"github.com/forkspacer/api-server/pkg/utils"
+	"github.com/forkspacer/api-server/pkg/metrics"
+	"github.com/forkspacer/api-server/pkg/tracing"
 	batchv1 "github.com/forkspacer/forkspacer/api/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
-	ctrl "sigs.k8s.io/controller-runtime"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type ForkspacerModuleService struct {
-	client client.Client
+	client     client.Client
+	namespaces NamespacePolicy
 }
 
-func NewForkspacerModuleService() (*ForkspacerModuleService, error) {
-	restConfig, err := ctrl.GetConfig()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
-	}
-
-	scheme := runtime.NewScheme()
-	if err := clientgoscheme.AddToScheme(scheme); err != nil {
-		return nil, fmt.Errorf("failed to add go client to schemes: %w", err)
-	}
-	if err := batchv1.AddToScheme(scheme); err != nil {
-		return nil, fmt.Errorf("failed to add batch.forkspacer.com/v1 to scheme: %w", err)
-	}
-
-	ctrlClient, err := client.New(restConfig, client.Options{Scheme: scheme})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create controller-runtime client: %w", err)
+func NewForkspacerModuleService(kubeClient client.Client, namespaces NamespacePolicy) *ForkspacerModuleService {
+	return &ForkspacerModuleService{
+		client:     metrics.InstrumentClient("module", kubeClient),
+		namespaces: namespaces,
 	}
-
-	return &ForkspacerModuleService{client: ctrlClient}, nil
 }
 
 type ModuleCreateIn struct {
@@ -51,15 +39,30 @@ type ModuleCreateIn struct {
 	Hibernated   bool
 }
 
-func (s ForkspacerModuleService) Create(ctx context.Context, moduleIn ModuleCreateIn) (*batchv1.Module, error) {
-	if moduleIn.Namespace == nil {
-		moduleIn.Namespace = utils.ToPtr("default")
+// Create creates the module. With client.DryRunAll among opts nothing is persisted,
+// and the returned module is the one the API server would have stored.
+func (s ForkspacerModuleService) Create(
+	ctx context.Context, moduleIn ModuleCreateIn, opts ...client.CreateOption,
+) (_ *batchv1.Module, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerModuleService.Create", nameAttribute(moduleIn.Name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	namespace, err := s.namespaces.Resolve(moduleIn.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Referenced objects must live in namespaces the API is allowed to touch as well
+	for _, referencedNamespace := range moduleReferencedNamespaces(moduleIn) {
+		if err := s.namespaces.Check(referencedNamespace); err != nil {
+			return nil, err
+		}
 	}
 
 	module := &batchv1.Module{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      moduleIn.Name,
-			Namespace: *moduleIn.Namespace,
+			Namespace: namespace,
 		},
 		Config: moduleIn.ConfigSchema,
 		Spec: batchv1.ModuleSpec{
@@ -84,70 +87,287 @@ func (s ForkspacerModuleService) Create(ctx context.Context, moduleIn ModuleCrea
 		}
 	}
 
-	return module, s.client.Create(ctx, module)
+	return module, s.client.Create(ctx, module, opts...)
+}
+
+// moduleReferencedNamespaces collects the namespaces of the host cluster objects a module refers to.
+func moduleReferencedNamespaces(moduleIn ModuleCreateIn) []string {
+	namespaces := []string{moduleIn.Workspace.Namespace}
+
+	helm := moduleIn.Helm
+	if helm == nil {
+		return namespaces
+	}
+
+	if helm.Chart.Repo != nil && helm.Chart.Repo.Auth != nil {
+		namespaces = append(namespaces, helm.Chart.Repo.Auth.Namespace)
+	}
+	if helm.Chart.ConfigMap != nil {
+		namespaces = append(namespaces, helm.Chart.ConfigMap.Namespace)
+	}
+	if helm.Chart.Git != nil && helm.Chart.Git.Auth != nil && helm.Chart.Git.Auth.HTTPSSecretRef != nil {
+		namespaces = append(namespaces, helm.Chart.Git.Auth.HTTPSSecretRef.Namespace)
+	}
+	for _, values := range helm.Values {
+		if values.ConfigMap != nil {
+			namespaces = append(namespaces, values.ConfigMap.Namespace)
+		}
+	}
+	for _, output := range helm.Outputs {
+		if output.ValueFrom != nil && output.ValueFrom.Secret != nil {
+			namespaces = append(namespaces, output.ValueFrom.Secret.Namespace)
+		}
+	}
+
+	return namespaces
 }
 
 type ModuleUpdateIn struct {
 	Name       string
 	Namespace  *string
 	Hibernated *bool
+
+	// ResourceVersion, when set, makes the update fail with ErrPreconditionFailed unless the module is still at it
+	ResourceVersion *string
 }
 
 func (s ForkspacerModuleService) Update(
 	ctx context.Context,
 	updateIn ModuleUpdateIn,
-) (*batchv1.Module, error) {
-	if updateIn.Namespace == nil {
-		updateIn.Namespace = utils.ToPtr("default")
+	opts ...client.UpdateOption,
+) (_ *batchv1.Module, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerModuleService.Update", nameAttribute(updateIn.Name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	namespace, err := s.namespaces.Resolve(updateIn.Namespace)
+	if err != nil {
+		return nil, err
 	}
 
 	module := &batchv1.Module{}
 
-	if err := s.client.Get(ctx, client.ObjectKey{
-		Name:      updateIn.Name,
-		Namespace: *updateIn.Namespace,
-	}, module); err != nil {
+	// Reads come from the cache, so a stale copy is retried once the cache caught up
+	return module, retry.RetryOnConflict(
+		retry.DefaultRetry,
+		func() error {
+			if err := s.client.Get(ctx, client.ObjectKey{
+				Name:      updateIn.Name,
+				Namespace: namespace,
+			}, module); err != nil {
+				return err
+			}
+			if err := checkResourceVersion(module, "modules", updateIn.ResourceVersion); err != nil {
+				return err
+			}
+
+			// Update only the Hibernated field
+			if updateIn.Hibernated != nil {
+				module.Spec.Hibernated = *updateIn.Hibernated
+			}
+
+			return s.client.Update(ctx, module, opts...)
+		},
+	)
+}
+
+func (s ForkspacerModuleService) Get(
+	ctx context.Context, name string, namespace *string,
+) (_ *batchv1.Module, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerModuleService.Get", nameAttribute(name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	resolvedNamespace, err := s.namespaces.Resolve(namespace)
+	if err != nil {
 		return nil, err
 	}
 
-	// Update only the Hibernated field
-	if updateIn.Hibernated != nil {
-		module.Spec.Hibernated = *updateIn.Hibernated
+	module := &batchv1.Module{}
+	if err := s.client.Get(ctx, client.ObjectKey{Name: name, Namespace: resolvedNamespace}, module); err != nil {
+		return nil, err
 	}
 
-	return module, s.client.Update(ctx, module)
+	return module, nil
 }
 
-func (s ForkspacerModuleService) Delete(ctx context.Context, name string, namespace *string) error {
-	if namespace == nil {
-		namespace = utils.ToPtr("default")
+// Delete removes the module. Options such as client.Preconditions are passed on to the API server.
+func (s ForkspacerModuleService) Delete(
+	ctx context.Context, name string, namespace *string, opts ...client.DeleteOption,
+) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerModuleService.Delete", nameAttribute(name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	resolvedNamespace, err := s.namespaces.Resolve(namespace)
+	if err != nil {
+		return err
 	}
 
 	module := &batchv1.Module{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
-			Namespace: *namespace,
+			Namespace: resolvedNamespace,
 		},
 	}
 
-	return s.client.Delete(ctx, module)
+	return s.client.Delete(ctx, module, opts...)
 }
 
 func (s ForkspacerModuleService) List(
 	ctx context.Context,
+	namespace *string,
 	limit int64,
 	continueToken *string,
-) (*batchv1.ModuleList, error) {
-	options := []client.ListOption{
-		client.Limit(limit),
+	sort ListSort,
+) (_ *batchv1.ModuleList, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerModuleService.List")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	options, err := s.namespaces.ListOptions(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := &batchv1.ModuleList{}
+	if err := s.client.List(ctx, modules, options...); err != nil {
+		return nil, err
 	}
 
-	if continueToken != nil {
-		options = append(options, client.Continue(*continueToken))
+	modules.Items, modules.Continue, err = paginateSorted(modules.Items, sort, modulePhase, limit, continueToken)
+
+	return modules, err
+}
+
+// CountByWorkspace counts the modules of each of workspaces, using the cache index on their workspace
+// reference, so that modules living in another namespace than their workspace are counted as well.
+// Modules in namespaces outside the policy are left out.
+func (s ForkspacerModuleService) CountByWorkspace(
+	ctx context.Context,
+	workspaces []ResourceReference,
+) (_ map[ResourceReference]int, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerModuleService.CountByWorkspace")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	counts := make(map[ResourceReference]int, len(workspaces))
+	for _, workspace := range workspaces {
+		modules := &batchv1.ModuleList{}
+		if err := s.client.List(ctx, modules,
+			client.MatchingFields{ModuleWorkspaceIndex: objectKey(workspace.Namespace, workspace.Name)},
+		); err != nil {
+			return nil, err
+		}
+
+		for _, module := range modules.Items {
+			if s.namespaces.Check(module.Namespace) == nil {
+				counts[workspace]++
+			}
+		}
+	}
+
+	return counts, nil
+}
+
+// ListByWorkspace returns the modules belonging to a workspace in the order of sort, using the cache
+// index on their workspace reference. Modules in namespaces outside the policy are left out.
+func (s ForkspacerModuleService) ListByWorkspace(
+	ctx context.Context,
+	workspace ResourceReference,
+	sort ListSort,
+) (_ *batchv1.ModuleList, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerModuleService.ListByWorkspace", nameAttribute(workspace.Name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if err := s.namespaces.Check(workspace.Namespace); err != nil {
+		return nil, err
 	}
 
 	modules := &batchv1.ModuleList{}
-	err := s.client.List(ctx, modules, options...)
+	if err := s.client.List(ctx, modules,
+		client.MatchingFields{ModuleWorkspaceIndex: objectKey(workspace.Namespace, workspace.Name)},
+	); err != nil {
+		return nil, err
+	}
+
+	modules.Items = slices.DeleteFunc(modules.Items, func(module batchv1.Module) bool {
+		return s.namespaces.Check(module.Namespace) != nil
+	})
+
+	// A single unlimited page, which is only sorted
+	modules.Items, _, err = paginateSorted(modules.Items, sort, modulePhase, 0, nil)
 
 	return modules, err
 }
+
+func modulePhase(module *batchv1.Module) string {
+	return string(module.Status.Phase)
+}
+
+// Patch applies a JSON merge patch or JSON patch to the module. validate is given the patched
+// module before it is sent, and aborts the patch by returning an error.
+func (s ForkspacerModuleService) Patch(
+	ctx context.Context,
+	name string, namespace *string,
+	patchIn PatchIn,
+	validate func(*batchv1.Module) error,
+	opts ...client.PatchOption,
+) (_ *batchv1.Module, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerModuleService.Patch", nameAttribute(name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	resolvedNamespace, err := s.namespaces.Resolve(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// A patch of the Helm values may point them at a ConfigMap, which must be in an allowed namespace
+	checkedValidate := func(module *batchv1.Module) error {
+		if err := s.checkReferencedNamespaces(module); err != nil {
+			return err
+		}
+		return validate(module)
+	}
+
+	return patchObject(
+		ctx, s.client, "modules", client.ObjectKey{Name: name, Namespace: resolvedNamespace}, patchIn, checkedValidate,
+		opts...,
+	)
+}
+
+// Apply creates or updates the module of a manifest with server-side apply. A manifest without
+// a namespace is applied to the default one. With client.DryRunAll among opts nothing is persisted.
+func (s ForkspacerModuleService) Apply(
+	ctx context.Context, manifest *unstructured.Unstructured, opts ...client.ApplyOption,
+) (_ *batchv1.Module, _ ApplyResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerModuleService.Apply", nameAttribute(manifest.GetName()))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	namespace, err := s.namespaces.Resolve(manifestNamespace(manifest))
+	if err != nil {
+		return nil, "", err
+	}
+	manifest.SetNamespace(namespace)
+
+	module := &batchv1.Module{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(manifest.Object, module); err != nil {
+		return nil, "", err
+	}
+
+	if err := s.checkReferencedNamespaces(module); err != nil {
+		return nil, "", err
+	}
+
+	return applyObject[batchv1.Module](ctx, s.client, manifest, opts...)
+}
+
+// checkReferencedNamespaces checks that the objects a module references live in namespaces the API is
+// allowed to touch as well.
+func (s ForkspacerModuleService) checkReferencedNamespaces(module *batchv1.Module) error {
+	for _, referencedNamespace := range moduleReferencedNamespaces(ModuleCreateIn{
+		Workspace: ResourceReference{Name: module.Spec.Workspace.Name, Namespace: module.Spec.Workspace.Namespace},
+		Helm:      module.Spec.Helm,
+	}) {
+		if err := s.namespaces.Check(referencedNamespace); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
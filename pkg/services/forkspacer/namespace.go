@@ -0,0 +1,83 @@
+package forkspacer
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var ErrNamespaceNotAllowed = errors.New("namespace is not allowed")
+
+// NamespacePolicy decides which namespace is used when a request omits one
+// and which namespaces the services are allowed to touch.
+type NamespacePolicy struct {
+	Default string
+	// Allowed holds path.Match patterns. An empty list allows every namespace.
+	Allowed []string
+}
+
+// Restricted reports whether the policy limits the namespaces the services may touch.
+func (p NamespacePolicy) Restricted() bool {
+	return len(p.Allowed) > 0
+}
+
+// CacheNamespaces returns the namespaces the cache can be limited to. It is empty, meaning every
+// namespace, unless the policy only allows namespaces named literally rather than by pattern.
+func (p NamespacePolicy) CacheNamespaces() []string {
+	for _, pattern := range p.Allowed {
+		if strings.ContainsAny(pattern, `*?[\`) {
+			return nil
+		}
+	}
+
+	return p.Allowed
+}
+
+// Check returns ErrNamespaceNotAllowed if namespace does not match any allowed pattern.
+func (p NamespacePolicy) Check(namespace string) error {
+	if !p.Restricted() {
+		return nil
+	}
+
+	for _, pattern := range p.Allowed {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrNamespaceNotAllowed, namespace)
+}
+
+// Resolve returns the requested namespace, or the default one when namespace is nil,
+// after checking it against the policy.
+func (p NamespacePolicy) Resolve(namespace *string) (string, error) {
+	resolved := p.Default
+	if namespace != nil {
+		resolved = *namespace
+	}
+
+	if err := p.Check(resolved); err != nil {
+		return "", err
+	}
+
+	return resolved, nil
+}
+
+// ListOptions returns the namespace scoping for list calls.
+// Without an explicit namespace an unrestricted policy lists across all namespaces,
+// while a restricted one falls back to the default namespace.
+func (p NamespacePolicy) ListOptions(namespace *string) ([]client.ListOption, error) {
+	if namespace == nil && !p.Restricted() {
+		return nil, nil
+	}
+
+	resolved, err := p.Resolve(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return []client.ListOption{client.InNamespace(resolved)}, nil
+}
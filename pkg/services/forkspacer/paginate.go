@@ -0,0 +1,157 @@
+package forkspacer
+
+import (
+	"cmp"
+	"encoding/base64"
+	"slices"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SortField is a field lists can be ordered by.
+type SortField string
+
+const (
+	SortByName              SortField = "name"
+	SortByNamespace         SortField = "namespace"
+	SortByCreationTimestamp SortField = "creationTimestamp"
+	SortByPhase             SortField = "phase"
+)
+
+var SortFields = []SortField{SortByName, SortByCreationTimestamp, SortByPhase, SortByNamespace}
+
+// ListSort orders a list before it is paginated. Ties are broken by namespace and name,
+// which is also the order of the zero value.
+type ListSort struct {
+	Field      SortField
+	Descending bool
+}
+
+// ParseListSort parses a sort field, prefixed with "-" for descending order.
+func ParseListSort(value string) (ListSort, bool) {
+	sort := ListSort{Field: SortField(strings.TrimPrefix(value, "-")), Descending: strings.HasPrefix(value, "-")}
+	return sort, slices.Contains(SortFields, sort.Field)
+}
+
+func (s ListSort) String() string {
+	if s.Descending {
+		return "-" + string(s.Field)
+	}
+
+	return string(s.Field)
+}
+
+// listCursor is the position of an item in a sorted list.
+type listCursor struct {
+	value string
+	key   string
+}
+
+// paginate returns a page of items listed from the cache, which ignores limits and continue tokens.
+// Items are ordered by namespace and name, and the continue token encodes the last key returned,
+// so a page stays stable while objects are added or removed.
+func paginate[T any, PT interface {
+	*T
+	metav1.Object
+}](items []T, limit int64, continueToken *string) ([]T, string, error) {
+	return paginateSorted[T, PT](items, ListSort{}, nil, limit, continueToken)
+}
+
+// paginateSorted is paginate in the order of sort. phase returns the phase of an item, for sorting by phase.
+// The continue token of a sorted list also encodes the sort and the sort value of the last item returned.
+func paginateSorted[T any, PT interface {
+	*T
+	metav1.Object
+}](items []T, sort ListSort, phase func(*T) string, limit int64, continueToken *string) ([]T, string, error) {
+	cursor := func(item *T) listCursor {
+		object := PT(item)
+		key := objectKey(object.GetNamespace(), object.GetName())
+
+		switch sort.Field {
+		case SortByName:
+			return listCursor{value: object.GetName(), key: key}
+		case SortByCreationTimestamp:
+			// Fixed-width UTC timestamps order like the times they stand for
+			return listCursor{value: object.GetCreationTimestamp().UTC().Format(time.RFC3339), key: key}
+		case SortByPhase:
+			return listCursor{value: phase(item), key: key}
+		default:
+			return listCursor{key: key}
+		}
+	}
+	compare := func(a, b listCursor) int {
+		result := cmp.Or(strings.Compare(a.value, b.value), strings.Compare(a.key, b.key))
+		if sort.Descending {
+			return -result
+		}
+		return result
+	}
+
+	slices.SortFunc(items, func(a, b T) int {
+		return compare(cursor(&a), cursor(&b))
+	})
+
+	start := 0
+	if continueToken != nil {
+		last, err := decodeContinueToken(*continueToken, sort)
+		if err != nil {
+			return nil, "", err
+		}
+
+		start, _ = slices.BinarySearchFunc(items, last, func(item T, target listCursor) int {
+			return compare(cursor(&item), target)
+		})
+		if start < len(items) && compare(cursor(&items[start]), last) == 0 {
+			start++
+		}
+	}
+
+	end := len(items)
+	if limit > 0 && int64(end-start) > limit {
+		end = start + int(limit)
+	}
+
+	page := items[start:end]
+	if end == len(items) {
+		return page, "", nil
+	}
+
+	return page, encodeContinueToken(cursor(&items[end-1]), sort), nil
+}
+
+// encodeContinueToken encodes the cursor of the last item returned. Tokens of the default order
+// only hold its key, as they did before lists could be sorted.
+func encodeContinueToken(last listCursor, sort ListSort) string {
+	if sort == (ListSort{}) {
+		return base64.RawURLEncoding.EncodeToString([]byte(last.key))
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(sort.String() + "\x00" + last.value + "\x00" + last.key))
+}
+
+func decodeContinueToken(continueToken string, sort ListSort) (listCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(continueToken)
+	if err != nil {
+		return listCursor{}, apierrors.NewBadRequest("invalid continue token")
+	}
+
+	parts := strings.Split(string(decoded), "\x00")
+	if sort == (ListSort{}) {
+		if len(parts) != 1 {
+			return listCursor{}, apierrors.NewBadRequest("continue token was issued for another sort")
+		}
+		return listCursor{key: parts[0]}, nil
+	}
+
+	if len(parts) != 3 {
+		return listCursor{}, apierrors.NewBadRequest("invalid continue token")
+	}
+	if parts[0] != sort.String() {
+		return listCursor{}, apierrors.NewBadRequest("continue token was issued for another sort")
+	}
+
+	return listCursor{value: parts[1], key: parts[2]}, nil
+}
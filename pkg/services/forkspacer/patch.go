@@ -0,0 +1,128 @@
+package forkspacer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PatchIn is a JSON merge patch or JSON patch of an object.
+type PatchIn struct {
+	Type types.PatchType
+	Data []byte
+
+	// ResourceVersion, when set, makes the patch fail with ErrPreconditionFailed unless the object is still at it
+	ResourceVersion *string
+}
+
+// patchObject applies a patch to the object with key, after validate accepted the result of applying it
+// to the current object. The patch sent carries the resourceVersion that was validated, so the object
+// is changed by the API server exactly as validated, or not at all and the patch is retried.
+func patchObject[T any, PT interface {
+	*T
+	client.Object
+}](
+	ctx context.Context,
+	kubeClient client.Client,
+	resource string,
+	key client.ObjectKey,
+	patchIn PatchIn,
+	validate func(PT) error,
+	opts ...client.PatchOption,
+) (PT, error) {
+	var patched PT
+
+	return patched, retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := PT(new(T))
+		if err := kubeClient.Get(ctx, key, current); err != nil {
+			return err
+		}
+		if err := checkResourceVersion(current, resource, patchIn.ResourceVersion); err != nil {
+			return err
+		}
+
+		preview := PT(new(T))
+		if err := applyPatch(current, preview, patchIn); err != nil {
+			return err
+		}
+		if err := validate(preview); err != nil {
+			return err
+		}
+
+		data, err := lockPatch(patchIn, current.GetResourceVersion())
+		if err != nil {
+			return err
+		}
+		if err := kubeClient.Patch(ctx, current, client.RawPatch(patchIn.Type, data), opts...); err != nil {
+			return err
+		}
+
+		patched = current
+		return nil
+	})
+}
+
+// applyPatch decodes into result the object patched locally, the way the API server patches it.
+func applyPatch(obj, result client.Object, patchIn PatchIn) error {
+	original, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	var patchedJSON []byte
+	switch patchIn.Type {
+	case types.MergePatchType:
+		patchedJSON, err = jsonpatch.MergePatch(original, patchIn.Data)
+	case types.JSONPatchType:
+		var patch jsonpatch.Patch
+		patch, err = jsonpatch.DecodePatch(patchIn.Data)
+		if err == nil {
+			patchedJSON, err = patch.Apply(original)
+		}
+	default:
+		return apierrors.NewBadRequest(fmt.Sprintf("unsupported patch type %q", patchIn.Type))
+	}
+	if err != nil {
+		return apierrors.NewBadRequest(fmt.Sprintf("the patch cannot be applied: %v", err))
+	}
+
+	if err := json.Unmarshal(patchedJSON, result); err != nil {
+		return apierrors.NewBadRequest(fmt.Sprintf("the patched object is invalid: %v", err))
+	}
+
+	return nil
+}
+
+// lockPatch adds the resourceVersion to a patch, which the API server then rejects with a conflict
+// when the object has changed since.
+func lockPatch(patchIn PatchIn, resourceVersion string) ([]byte, error) {
+	switch patchIn.Type {
+	case types.MergePatchType:
+		var patch map[string]any
+		if err := json.Unmarshal(patchIn.Data, &patch); err != nil {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid merge patch: %v", err))
+		}
+		metadata, _ := patch["metadata"].(map[string]any)
+		if metadata == nil {
+			metadata = map[string]any{}
+		}
+		metadata["resourceVersion"] = resourceVersion
+		patch["metadata"] = metadata
+		return json.Marshal(patch)
+	default:
+		var patch []any
+		if err := json.Unmarshal(patchIn.Data, &patch); err != nil {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid JSON patch: %v", err))
+		}
+		patch = append(patch, map[string]any{
+			"op": "replace", "path": "/metadata/resourceVersion", "value": resourceVersion,
+		})
+		return json.Marshal(patch)
+	}
+}
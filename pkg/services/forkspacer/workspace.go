@@ -2,58 +2,49 @@ package forkspacer
 
 import (
 	"context"
-	"fmt"
 
-	"github.com/forkspacer/api-server/pkg/utils"
+	"github.com/forkspacer/api-server/pkg/metrics"
+	"github.com/forkspacer/api-server/pkg/tracing"
 	batchv1 "github.com/forkspacer/forkspacer/api/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/util/retry"
-	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type ForkspacerWorkspaceService struct {
-	client client.Client
+	client     client.Client
+	namespaces NamespacePolicy
 }
 
-func NewForkspacerWorkspaceService() (*ForkspacerWorkspaceService, error) {
-	restConfig, err := ctrl.GetConfig()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
-	}
-
-	scheme := runtime.NewScheme()
-	if err := clientgoscheme.AddToScheme(scheme); err != nil {
-		return nil, fmt.Errorf("failed to add go client to schemes: %w", err)
-	}
-	if err := batchv1.AddToScheme(scheme); err != nil {
-		return nil, fmt.Errorf("failed to add batch.forkspacer.com/v1 to scheme: %w", err)
-	}
-
-	ctrlClient, err := client.New(restConfig, client.Options{Scheme: scheme})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create controller-runtime client: %w", err)
+func NewForkspacerWorkspaceService(kubeClient client.Client, namespaces NamespacePolicy) *ForkspacerWorkspaceService {
+	return &ForkspacerWorkspaceService{
+		client:     metrics.InstrumentClient("workspace", kubeClient),
+		namespaces: namespaces,
 	}
-
-	return &ForkspacerWorkspaceService{client: ctrlClient}, nil
 }
 
 func (s ForkspacerWorkspaceService) CreateKubeconfigSecret(
 	ctx context.Context,
 	name string, namespace *string,
 	kubeconfigData []byte,
-) (*corev1.Secret, error) {
-	if namespace == nil {
-		namespace = utils.ToPtr("default")
+	opts ...client.CreateOption,
+) (_ *corev1.Secret, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerWorkspaceService.CreateKubeconfigSecret", nameAttribute(name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	resolvedNamespace, err := s.namespaces.Resolve(namespace)
+	if err != nil {
+		return nil, err
 	}
 
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
-			Namespace: *namespace,
+			Namespace: resolvedNamespace,
 			Labels: map[string]string{
 				BaseLabel: Labels.WorkspaceKubeconfigSecret,
 			},
@@ -64,45 +55,58 @@ func (s ForkspacerWorkspaceService) CreateKubeconfigSecret(
 		},
 	}
 
-	return secret, s.client.Create(ctx, secret)
+	return secret, s.client.Create(ctx, secret, opts...)
 }
 
 func (s ForkspacerWorkspaceService) DeleteKubeconfigSecret(
 	ctx context.Context,
 	name string, namespace *string,
-) error {
-	if namespace == nil {
-		namespace = utils.ToPtr("default")
+	opts ...client.DeleteOption,
+) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerWorkspaceService.DeleteKubeconfigSecret", nameAttribute(name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	resolvedNamespace, err := s.namespaces.Resolve(namespace)
+	if err != nil {
+		return err
 	}
 
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: *namespace,
-			Labels: map[string]string{
-				BaseLabel: Labels.WorkspaceKubeconfigSecret,
-			},
-		},
+	secret := &corev1.Secret{}
+	if err := s.client.Get(ctx, client.ObjectKey{Name: name, Namespace: resolvedNamespace}, secret); err != nil {
+		return err
+	}
+	// Any other secret is reported as missing, so that it can be neither deleted nor detected
+	if secret.Labels[BaseLabel] != Labels.WorkspaceKubeconfigSecret {
+		return apierrors.NewNotFound(corev1.Resource("secrets"), name)
 	}
 
-	return s.client.Delete(ctx, secret)
+	// The preconditions keep the secret from being replaced by another one between the check and the delete
+	opts = append(opts, client.Preconditions{UID: &secret.UID, ResourceVersion: &secret.ResourceVersion})
+
+	return s.client.Delete(ctx, secret, opts...)
 }
 
 func (s ForkspacerWorkspaceService) ListKubeconfigSecrets(
 	ctx context.Context,
+	namespace *string,
 	limit int64, continueToken *string,
-) (*corev1.SecretList, error) {
-	options := []client.ListOption{
-		client.MatchingLabels{BaseLabel: Labels.WorkspaceKubeconfigSecret},
-		client.Limit(limit),
-	}
+) (_ *corev1.SecretList, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerWorkspaceService.ListKubeconfigSecrets")
+	defer func() { tracing.EndSpan(span, err) }()
 
-	if continueToken != nil {
-		options = append(options, client.Continue(*continueToken))
+	options, err := s.namespaces.ListOptions(namespace)
+	if err != nil {
+		return nil, err
 	}
 
+	options = append(options, client.MatchingLabels{BaseLabel: Labels.WorkspaceKubeconfigSecret})
+
 	secrets := &corev1.SecretList{}
-	err := s.client.List(ctx, secrets, options...)
+	if err := s.client.List(ctx, secrets, options...); err != nil {
+		return nil, err
+	}
+
+	secrets.Items, secrets.Continue, err = paginate(secrets.Items, limit, continueToken)
 
 	return secrets, err
 }
@@ -135,11 +139,29 @@ type WorkspaceCreateIn struct {
 	AutoHibernation *WorkspaceAutoHibernationIn
 }
 
+// Create creates the workspace. With client.DryRunAll among opts nothing is persisted,
+// and the returned workspace is the one the API server would have stored.
 func (s ForkspacerWorkspaceService) Create(
-	ctx context.Context, workspaceIn WorkspaceCreateIn,
-) (*batchv1.Workspace, error) {
-	if workspaceIn.Namespace == nil {
-		workspaceIn.Namespace = utils.ToPtr("default")
+	ctx context.Context, workspaceIn WorkspaceCreateIn, opts ...client.CreateOption,
+) (_ *batchv1.Workspace, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerWorkspaceService.Create", nameAttribute(workspaceIn.Name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	namespace, err := s.namespaces.Resolve(workspaceIn.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Referenced objects must live in namespaces the API is allowed to touch as well
+	if workspaceIn.From != nil {
+		if err := s.namespaces.Check(workspaceIn.From.Namespace); err != nil {
+			return nil, err
+		}
+	}
+	if workspaceIn.Connection != nil && workspaceIn.Connection.Secret != nil {
+		if err := s.namespaces.Check(workspaceIn.Connection.Secret.Namespace); err != nil {
+			return nil, err
+		}
 	}
 
 	// Set default workspace type to kubernetes if not specified
@@ -151,7 +173,7 @@ func (s ForkspacerWorkspaceService) Create(
 	workspace := &batchv1.Workspace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      workspaceIn.Name,
-			Namespace: *workspaceIn.Namespace,
+			Namespace: namespace,
 		},
 		Spec: batchv1.WorkspaceSpec{
 			Type:       workspaceType,
@@ -195,57 +217,103 @@ func (s ForkspacerWorkspaceService) Create(
 		}
 	}
 
-	return workspace, s.client.Create(ctx, workspace)
+	return workspace, s.client.Create(ctx, workspace, opts...)
+}
+
+func (s ForkspacerWorkspaceService) Get(
+	ctx context.Context, name string, namespace *string,
+) (_ *batchv1.Workspace, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerWorkspaceService.Get", nameAttribute(name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	resolvedNamespace, err := s.namespaces.Resolve(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace := &batchv1.Workspace{}
+	if err := s.client.Get(ctx, client.ObjectKey{Name: name, Namespace: resolvedNamespace}, workspace); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
 }
 
-func (s ForkspacerWorkspaceService) Delete(ctx context.Context, name string, namespace *string) error {
-	if namespace == nil {
-		namespace = utils.ToPtr("default")
+// Delete removes the workspace. Options such as client.Preconditions are passed on to the API server.
+func (s ForkspacerWorkspaceService) Delete(
+	ctx context.Context, name string, namespace *string, opts ...client.DeleteOption,
+) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerWorkspaceService.Delete", nameAttribute(name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	resolvedNamespace, err := s.namespaces.Resolve(namespace)
+	if err != nil {
+		return err
 	}
 
 	workspace := &batchv1.Workspace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
-			Namespace: *namespace,
+			Namespace: resolvedNamespace,
 		},
 	}
 
-	return s.client.Delete(ctx, workspace)
+	return s.client.Delete(ctx, workspace, opts...)
 }
 
 func (s ForkspacerWorkspaceService) List(
 	ctx context.Context,
+	namespace *string,
 	limit int64,
 	continueToken *string,
-) (*batchv1.WorkspaceList, error) {
-	options := []client.ListOption{
-		client.Limit(limit),
-	}
+	sort ListSort,
+) (_ *batchv1.WorkspaceList, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerWorkspaceService.List")
+	defer func() { tracing.EndSpan(span, err) }()
 
-	if continueToken != nil {
-		options = append(options, client.Continue(*continueToken))
+	options, err := s.namespaces.ListOptions(namespace)
+	if err != nil {
+		return nil, err
 	}
 
 	workspaces := &batchv1.WorkspaceList{}
-	err := s.client.List(ctx, workspaces, options...)
+	if err := s.client.List(ctx, workspaces, options...); err != nil {
+		return nil, err
+	}
+
+	workspaces.Items, workspaces.Continue, err = paginateSorted(
+		workspaces.Items, sort, workspacePhase, limit, continueToken,
+	)
 
 	return workspaces, err
 }
 
+func workspacePhase(workspace *batchv1.Workspace) string {
+	return string(workspace.Status.Phase)
+}
+
 type WorkspaceUpdateIn struct {
 	Name            string
 	Namespace       *string
 	Hibernated      *bool
 	AutoHibernation *WorkspaceAutoHibernationIn
 	ManagedCluster  *ManagedClusterIn
+
+	// ResourceVersion, when set, makes the update fail with ErrPreconditionFailed unless the workspace is still at it
+	ResourceVersion *string
 }
 
 func (s ForkspacerWorkspaceService) Update(
 	ctx context.Context,
 	updateIn WorkspaceUpdateIn,
-) (*batchv1.Workspace, error) {
-	if updateIn.Namespace == nil {
-		updateIn.Namespace = utils.ToPtr("default")
+	opts ...client.UpdateOption,
+) (_ *batchv1.Workspace, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerWorkspaceService.Update", nameAttribute(updateIn.Name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	namespace, err := s.namespaces.Resolve(updateIn.Namespace)
+	if err != nil {
+		return nil, err
 	}
 
 	workspace := &batchv1.Workspace{}
@@ -256,10 +324,13 @@ func (s ForkspacerWorkspaceService) Update(
 
 			if err := s.client.Get(ctx, client.ObjectKey{
 				Name:      updateIn.Name,
-				Namespace: *updateIn.Namespace,
+				Namespace: namespace,
 			}, workspace); err != nil {
 				return err
 			}
+			if err := checkResourceVersion(workspace, "workspaces", updateIn.ResourceVersion); err != nil {
+				return err
+			}
 
 			// Update only the allowed fields
 			if updateIn.Hibernated != nil {
@@ -284,7 +355,63 @@ func (s ForkspacerWorkspaceService) Update(
 				}
 			}
 
-			return s.client.Update(ctx, workspace)
+			return s.client.Update(ctx, workspace, opts...)
 		},
 	)
 }
+
+// Patch applies a JSON merge patch or JSON patch to the workspace. validate is given the patched
+// workspace before it is sent, and aborts the patch by returning an error.
+func (s ForkspacerWorkspaceService) Patch(
+	ctx context.Context,
+	name string, namespace *string,
+	patchIn PatchIn,
+	validate func(*batchv1.Workspace) error,
+	opts ...client.PatchOption,
+) (_ *batchv1.Workspace, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerWorkspaceService.Patch", nameAttribute(name))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	resolvedNamespace, err := s.namespaces.Resolve(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return patchObject(
+		ctx, s.client, "workspaces", client.ObjectKey{Name: name, Namespace: resolvedNamespace}, patchIn, validate, opts...,
+	)
+}
+
+// Apply creates or updates the workspace of a manifest with server-side apply. A manifest without
+// a namespace is applied to the default one. With client.DryRunAll among opts nothing is persisted.
+func (s ForkspacerWorkspaceService) Apply(
+	ctx context.Context, manifest *unstructured.Unstructured, opts ...client.ApplyOption,
+) (_ *batchv1.Workspace, _ ApplyResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ForkspacerWorkspaceService.Apply", nameAttribute(manifest.GetName()))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	namespace, err := s.namespaces.Resolve(manifestNamespace(manifest))
+	if err != nil {
+		return nil, "", err
+	}
+	manifest.SetNamespace(namespace)
+
+	workspace := &batchv1.Workspace{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(manifest.Object, workspace); err != nil {
+		return nil, "", err
+	}
+
+	// Referenced objects must live in namespaces the API is allowed to touch as well
+	if workspace.Spec.From != nil {
+		if err := s.namespaces.Check(workspace.Spec.From.Namespace); err != nil {
+			return nil, "", err
+		}
+	}
+	if workspace.Spec.Connection.SecretReference != nil {
+		if err := s.namespaces.Check(workspace.Spec.Connection.SecretReference.Namespace); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return applyObject[batchv1.Workspace](ctx, s.client, manifest, opts...)
+}
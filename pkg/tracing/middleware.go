@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/forkspacer/api-server/pkg/api/response"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// untracedPaths are polled by infrastructure and would only add noise.
+var untracedPaths = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// HTTPMiddleware starts a server span for every request, continuing any incoming W3C trace context.
+// Spans are named after the chi route pattern and the trace ID is returned in the trace ID header.
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if spanContext := span.SpanContext(); spanContext.HasTraceID() {
+				w.Header().Set(response.TraceIDHeader, spanContext.TraceID().String())
+			}
+
+			next.ServeHTTP(w, r)
+
+			// The route pattern is only complete once routing has finished
+			if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil && routeCtx.RoutePattern() != "" {
+				span.SetName(r.Method + " " + routeCtx.RoutePattern())
+				span.SetAttributes(attribute.String("http.route", routeCtx.RoutePattern()))
+			}
+		}),
+		"http.request",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string { return r.Method }),
+		otelhttp.WithFilter(func(r *http.Request) bool { return !untracedPaths[r.URL.Path] }),
+	)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 )
 
 var (
@@ -53,3 +54,24 @@ func GetEnvOr[T ParseStringSupportTypes](envName string, defaultValue T) (T, err
 
 	return parsedEnv, nil
 }
+
+// GetEnvListOr retrieves the environment variable named by the key envName
+// and splits its value on commas, trimming whitespace and dropping empty items.
+//
+// If the environment variable is not set, it returns the provided default value
+// and ErrEnvNotFound.
+func GetEnvListOr(envName string, defaultValue []string) ([]string, error) {
+	envStr := os.Getenv(envName)
+	if envStr == "" {
+		return defaultValue, ErrEnvNotFound
+	}
+
+	var values []string
+	for item := range strings.SplitSeq(envStr, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+
+	return values, nil
+}
@@ -1,7 +1,52 @@
 package version
 
+import (
+	"runtime"
+	"runtime/debug"
+)
+
 var (
 	Version   = "unknown" // Set via ldflags
 	GitCommit = "unknown" // Set via ldflags
 	BuildDate = "unknown" // Set via ldflags
 )
+
+// forkspacerModule provides the CRD types the API server is built against.
+const forkspacerModule = "github.com/forkspacer/forkspacer"
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	// ForkspacerVersion is the version of the Forkspacer module providing the CRD types.
+	ForkspacerVersion string `json:"forkspacerVersion"`
+}
+
+func Info() BuildInfo {
+	info := BuildInfo{
+		Version:           Version,
+		GitCommit:         GitCommit,
+		BuildDate:         BuildDate,
+		GoVersion:         runtime.Version(),
+		ForkspacerVersion: "unknown",
+	}
+
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	for _, dependency := range buildInfo.Deps {
+		if dependency.Path != forkspacerModule {
+			continue
+		}
+
+		info.ForkspacerVersion = dependency.Version
+		if dependency.Replace != nil && dependency.Replace.Version != "" {
+			info.ForkspacerVersion = dependency.Replace.Version
+		}
+	}
+
+	return info
+}